@@ -8,8 +8,36 @@ import (
 	"runtime"
 )
 
+// zxingCPPVersion is the zxing-cpp release the C wrapper is written against.
+// src/zxing.cpp uses the CreateBarcodeFromText/WriteBarcodeToImage writer API
+// introduced in 3.0, so every build checks out this tag before compiling.
+const zxingCPPVersion = "v3.0.2"
+
+// checkoutZXingCPP initializes the zxing-cpp submodule and checks out zxingCPPVersion.
+func checkoutZXingCPP(root string) error {
+	fmt.Println("Updating git submodules...")
+	cmd := exec.Command("git", "submodule", "update", "--init", "--recursive")
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git submodule update failed: %w", err)
+	}
+
+	fmt.Printf("Checking out zxing-cpp %s...\n", zxingCPPVersion)
+	cmd = exec.Command("git", "-C", "zxing-cpp", "checkout", "--quiet", zxingCPPVersion)
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git checkout zxing-cpp %s failed: %w", zxingCPPVersion, err)
+	}
+	return nil
+}
+
 // buildLib builds C++ static libraries via CMake.
-// It runs git submodule update, cmake, and make, then copies artifacts to lib/{os}-{arch}/.
+// It pins the zxing-cpp submodule, runs cmake and make, then copies artifacts to lib/{os}-{arch}/
+// and regenerates the BUILDINFO next to them.
 func buildLib(args []string) error {
 	// Check build dependencies before starting
 	if _, err := exec.LookPath("cmake"); err != nil {
@@ -28,14 +56,9 @@ func buildLib(args []string) error {
 		return err
 	}
 
-	// Step 1: Update git submodules
-	fmt.Println("Updating git submodules...")
-	cmd := exec.Command("git", "submodule", "update", "--init", "--recursive")
-	cmd.Dir = root
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git submodule update failed: %w", err)
+	// Step 1: Update git submodules and pin zxing-cpp
+	if err := checkoutZXingCPP(root); err != nil {
+		return err
 	}
 
 	// Step 2: Create build directory
@@ -55,7 +78,7 @@ func buildLib(args []string) error {
 		generator = "MinGW Makefiles"
 	}
 
-	cmd = exec.Command("cmake", "-G", generator, "-DCMAKE_BUILD_TYPE=Release", root)
+	cmd := exec.Command("cmake", "-G", generator, "-DCMAKE_BUILD_TYPE=Release", root)
 	cmd.Dir = buildDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -87,8 +110,7 @@ func buildLib(args []string) error {
 
 	for _, src := range artifacts {
 		if _, err := os.Stat(src); err != nil {
-			fmt.Printf("Warning: artifact not found: %s\n", src)
-			continue
+			return fmt.Errorf("artifact not found: %s", src)
 		}
 		dst := filepath.Join(libPath, filepath.Base(src))
 		if err := copyFile(src, dst); err != nil {
@@ -97,6 +119,15 @@ func buildLib(args []string) error {
 		fmt.Printf("Copied: %s -> %s\n", src, dst)
 	}
 
+	// Step 6: Record the new archives in BUILDINFO
+	header := []string{
+		fmt.Sprintf("Build: %s-%s (cmd/build build-lib)", runtime.GOOS, detectArch()),
+		"CMake: -DCMAKE_BUILD_TYPE=Release",
+	}
+	if err := writeBuildInfo(root, libPath, header); err != nil {
+		return err
+	}
+
 	fmt.Println("Build complete.")
 	return nil
 }
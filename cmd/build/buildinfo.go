@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// libDescriptions names the static libraries listed in BUILDINFO, in order.
+var libDescriptions = []struct {
+	name string
+	desc string
+}{
+	{"libZXing.a", "ZXing-CPP core static library"},
+	{"libzxingwrapper.a", "CGO wrapper static library"},
+}
+
+var abiVersionRe = regexp.MustCompile(`(?m)^#define\s+ZXING_ABI_VERSION\s+(\d+)`)
+
+// headerABIVersion returns ZXING_ABI_VERSION from include/zxing.h.
+func headerABIVersion(root string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(root, "include", "zxing.h"))
+	if err != nil {
+		return 0, err
+	}
+	m := abiVersionRe.FindSubmatch(data)
+	if m == nil {
+		return 0, fmt.Errorf("ZXING_ABI_VERSION not found in include/zxing.h")
+	}
+	return strconv.Atoi(string(m[1]))
+}
+
+// writeBuildInfo rewrites libPath/BUILDINFO for the archives just copied there.
+// Builder lines (Build, Builder, Toolchain, ...) of an existing file are kept;
+// Date, zxing-cpp, ABI and the Libraries section are regenerated so the file
+// always matches the archives next to it.
+func writeBuildInfo(root, libPath string, header []string) error {
+	abi, err := headerABIVersion(root)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(libPath, "BUILDINFO")
+	if existing, err := readBuildInfoHeader(path); err == nil && len(existing) > 0 {
+		header = existing
+	}
+
+	var b strings.Builder
+	for _, line := range header {
+		switch {
+		case strings.HasPrefix(line, "Date:"), strings.HasPrefix(line, "zxing-cpp:"), strings.HasPrefix(line, "ABI:"):
+			continue
+		}
+		b.WriteString(line + "\n")
+		if strings.HasPrefix(line, "Build:") {
+			fmt.Fprintf(&b, "Date: %s\n", time.Now().Format("2006-01-02"))
+			fmt.Fprintf(&b, "zxing-cpp: %s\n", zxingCPPVersion)
+			fmt.Fprintf(&b, "ABI: %d\n", abi)
+		}
+	}
+
+	b.WriteString("Libraries:\n")
+	for _, lib := range libDescriptions {
+		data, err := os.ReadFile(filepath.Join(libPath, lib.name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", lib.name, err)
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "  %s - %s (%s bytes)\n", lib.name, lib.desc, groupDigits(len(data)))
+		fmt.Fprintf(&b, "    SHA-256: %s\n", hex.EncodeToString(sum[:]))
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write BUILDINFO: %w", err)
+	}
+	fmt.Printf("Updated: %s\n", path)
+	return nil
+}
+
+// readBuildInfoHeader returns the lines of a BUILDINFO file before its Libraries section.
+func readBuildInfoHeader(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Libraries:") {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// groupDigits formats n with comma thousands separators.
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteBuildInfo(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "include"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "include", "zxing.h"), []byte("#define ZXING_ABI_VERSION 7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	libPath := filepath.Join(root, "lib")
+	if err := os.MkdirAll(libPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale := "Build: Linux x64 (native compile)\nDate: 2000-01-01\nzxing-cpp: v0.0.0\nBuilder: test\n" +
+		"Libraries:\n  libZXing.a - stale (1 bytes)\n"
+	if err := os.WriteFile(filepath.Join(libPath, "BUILDINFO"), []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"libZXing.a", "libzxingwrapper.a"} {
+		if err := os.WriteFile(filepath.Join(libPath, name), make([]byte, 1234), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writeBuildInfo(root, libPath, []string{"Build: unused"}); err != nil {
+		t.Fatalf("writeBuildInfo() failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(libPath, "BUILDINFO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := string(data)
+
+	for _, want := range []string{
+		"Build: Linux x64 (native compile)\n",
+		"Builder: test\n",
+		"zxing-cpp: " + zxingCPPVersion + "\n",
+		"ABI: 7\n",
+		"libzxingwrapper.a - CGO wrapper static library (1,234 bytes)",
+		// SHA-256 of 1234 zero bytes
+		"SHA-256: ad47fd9e87159d651a53b3dfba3ef200684a9ed88c2528b62e18f3881fe203b0",
+	} {
+		if !strings.Contains(info, want) {
+			t.Errorf("BUILDINFO missing %q:\n%s", want, info)
+		}
+	}
+	for _, stale := range []string{"2000-01-01", "v0.0.0", "stale", "Build: unused"} {
+		if strings.Contains(info, stale) {
+			t.Errorf("BUILDINFO still contains %q:\n%s", stale, info)
+		}
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1,000", 2905570: "2,905,570"}
+	for n, want := range tests {
+		if got := groupDigits(n); got != want {
+			t.Errorf("groupDigits(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
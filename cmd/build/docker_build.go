@@ -10,7 +10,7 @@ import (
 // dockerBuild builds Linux static library in a CentOS 7 Docker container.
 // This ensures glibc 2.17 compatibility for the precompiled library.
 // The container includes devtoolset-10 (GCC 10), cmake3, and Go 1.24.
-// zxing-cpp v3.0.2 requires C++20 features patched by patch_using_enum.sh.
+// zxing-cpp (zxingCPPVersion) requires C++20 features patched by patch_using_enum.sh.
 func dockerBuild(args []string) error {
 	root, err := projectRoot()
 	if err != nil {
@@ -24,6 +24,11 @@ func dockerBuild(args []string) error {
 		return fmt.Errorf("Dockerfile not found: %s", dockerfilePath)
 	}
 
+	// The container builds the zxing-cpp checkout mounted from root
+	if err := checkoutZXingCPP(root); err != nil {
+		return err
+	}
+
 	// Build Docker image
 	fmt.Println("Building Docker image...")
 	cmd := exec.Command("docker", "build", "-t", "zxing-linux-build", "-f", dockerfilePath, filepath.Join(root, "docker"))
@@ -56,6 +61,18 @@ func dockerBuild(args []string) error {
 		return fmt.Errorf("docker run failed: %w", err)
 	}
 
+	header := []string{
+		"Build: Linux x64 (native compile)",
+		"Builder: CentOS 7 (glibc 2.17) + devtoolset-10 (GCC 10.2.1)",
+		"Toolchain: docker/Dockerfile.linux-build + docker/patch_using_enum.sh",
+		"CMake: -DBUILD_STATIC_LIB=ON -DBUILD_SHARED_LIBS=OFF -DCMAKE_BUILD_TYPE=Release -DCMAKE_CXX_STANDARD=20 -DCMAKE_CXX_FLAGS=-fcoroutines",
+		"Patches: using enum BarcodeFormat -> static constexpr (GCC 10 compatibility)",
+		"Format: ELF x86-64 (Linux static archive, glibc 2.17 compatible)",
+	}
+	if err := writeBuildInfo(root, libDir, header); err != nil {
+		return err
+	}
+
 	fmt.Println("Docker build complete.")
 	return nil
 }
@@ -28,6 +28,10 @@ func buildWasm(args []string) error {
 		return err
 	}
 
+	if err := checkoutZXingCPP(root); err != nil {
+		return err
+	}
+
 	// Backup CMakeLists.txt
 	cmakeFile := filepath.Join(root, "CMakeLists.txt")
 	cmakeWasmFile := filepath.Join(root, "CMakeLists-wasm.txt")
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"image"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/chennqqi/zxing/pkg/zxing"
@@ -19,8 +20,17 @@ const maxUploadSize = 10 * 1024 * 1024 // 10MB
 
 // DecodeResult represents a single barcode decode result.
 type DecodeResult struct {
-	Text   string `json:"text"`
-	Format string `json:"format"`
+	Text                string `json:"text"`
+	Format              string `json:"format"`
+	SymbologyIdentifier string `json:"symbology_identifier,omitempty"`
+	ECLevel             string `json:"ec_level,omitempty"`
+	Version             string `json:"version,omitempty"`
+	Orientation         int    `json:"orientation"`
+	ContentType         string `json:"content_type"`
+	AddOn               string `json:"add_on,omitempty"`
+	// Error and Points are set for barcodes that were found but could not be decoded.
+	Error  string        `json:"error,omitempty"`
+	Points []image.Point `json:"points,omitempty"`
 }
 
 // DecodeResponse represents the API response for decode endpoint.
@@ -30,13 +40,57 @@ type DecodeResponse struct {
 	Results []*DecodeResult `json:"results,omitempty"`
 }
 
-// 解码选项
+// 解码选项；未提供的开关使用 zxing.DefaultDecodeOptions 的默认值
 type DecodeRequest struct {
 	Formats      []string `json:"formats"`
-	TryHarder    bool     `json:"try_harder"`
-	TryRotate    bool     `json:"try_rotate"`
-	TryInvert    bool     `json:"try_invert"`
-	TryDownscale bool     `json:"try_downscale"`
+	TryHarder    *bool    `json:"try_harder"`
+	TryRotate    *bool    `json:"try_rotate"`
+	TryInvert    *bool    `json:"try_invert"`
+	TryDownscale *bool    `json:"try_downscale"`
+	ReturnErrors bool     `json:"return_errors"`
+	TextMode     string   `json:"text_mode"`
+	CharacterSet string   `json:"character_set"`
+	ReadAddOn    bool     `json:"read_add_on"`
+	Region       *Region  `json:"region"`
+	// Multi 为 true 时返回图片中的所有条码，否则只返回第一个
+	Multi bool `json:"multi"`
+}
+
+// 识别区域，坐标以图片左上角为原点
+type Region struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// decodeOptions builds decode options from the request, keeping defaults for omitted fields.
+func (req *DecodeRequest) decodeOptions(formats zxing.Format, textMode zxing.TextMode) *zxing.DecodeOptions {
+	opts := zxing.DefaultDecodeOptions()
+	opts.PossibleFormats = formats
+	opts.TextMode = textMode
+	opts.CharacterSet = req.CharacterSet
+	if req.ReadAddOn {
+		opts.EANAddOn = zxing.EANAddOnRead
+	}
+	opts.ReturnErrors = req.ReturnErrors
+	opts.TryRotate = req.TryRotate
+	opts.TryDownscale = req.TryDownscale
+	if r := req.Region; r != nil {
+		opts.Region = image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+	}
+	for _, flag := range []struct {
+		dst *bool
+		val *bool
+	}{
+		{&opts.TryHarder, req.TryHarder},
+		{&opts.TryInvert, req.TryInvert},
+	} {
+		if flag.val != nil {
+			*flag.dst = *flag.val
+		}
+	}
+	return opts
 }
 
 func main() {
@@ -46,6 +100,13 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// 所有请求共用一个 ZXing 实例，配置读取 ZXING_* 环境变量，由 Config.Concurrency 控制并行解码数
+	zx, err := zxing.New(zxing.LoadConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to create ZXing instance: %v", err)
+	}
+	defer zx.Close()
+
 	// 创建 Gin 路由
 	r := gin.Default()
 
@@ -73,6 +134,29 @@ func main() {
 			return
 		}
 
+		// 解析条码格式
+		formats, err := zxing.ParseFormats(strings.Join(req.Formats, ","))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, DecodeResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid formats: %v", err),
+			})
+			return
+		}
+
+		// 解析文本输出模式，默认 HRI
+		textMode := zxing.TextModeHRI
+		if req.TextMode != "" {
+			textMode, err = zxing.ParseTextMode(req.TextMode)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, DecodeResponse{
+					Success: false,
+					Message: fmt.Sprintf("Invalid text mode: %v", err),
+				})
+				return
+			}
+		}
+
 		// 获取上传的文件
 		file, err := c.FormFile("image")
 		if err != nil {
@@ -123,29 +207,29 @@ func main() {
 			return
 		}
 
-		// Create ZXing instance
-		zx, err := zxing.New(nil)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, DecodeResponse{
-				Success: false,
-				Message: fmt.Sprintf("Failed to create ZXing instance: %v", err),
-			})
-			return
-		}
-		defer zx.Close()
-
 		// Create decode options
-		opts := &zxing.DecodeOptions{
-			TryHarder: req.TryHarder,
-		}
-		if len(req.Formats) > 0 {
-			opts.PossibleFormats = req.Formats
-		}
+		opts := req.decodeOptions(formats, textMode)
 
-		// Decode the image
-		result, err := zx.DecodeImage(context.Background(), img, opts)
+		// Decode the first barcode, or every barcode when requested; a client
+		// that disconnects cancels the decode
+		var results []*zxing.Result
+		if req.Multi {
+			results, err = zx.DecodeMultiImage(c.Request.Context(), img, opts)
+		} else {
+			var result *zxing.Result
+			result, err = zx.DecodeImage(c.Request.Context(), img, opts)
+			results = []*zxing.Result{result}
+		}
 		if err != nil {
-			c.JSON(http.StatusOK, DecodeResponse{
+			// "No barcode" is a normal outcome; anything else means the backend failed
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, zxing.ErrNotFound), errors.Is(err, zxing.ErrChecksum), errors.Is(err, zxing.ErrFormat):
+				status = http.StatusOK
+			case errors.Is(err, zxing.ErrInvalidInput):
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, DecodeResponse{
 				Success: false,
 				Message: fmt.Sprintf("Decode failed: %v", err),
 			})
@@ -154,11 +238,30 @@ func main() {
 
 		// Convert to API response
 		var apiResults []*DecodeResult
-		if result != nil && len(result.Text) > 0 {
-			apiResults = append(apiResults, &DecodeResult{
-				Text:   result.Text,
-				Format: result.Format,
-			})
+		for _, result := range results {
+			if result == nil {
+				continue
+			}
+			if result.Error != nil {
+				apiResults = append(apiResults, &DecodeResult{
+					Format: result.Format.String(),
+					Error:  result.Error.Error(),
+					Points: result.Points,
+				})
+				continue
+			}
+			if len(result.Text) > 0 {
+				apiResults = append(apiResults, &DecodeResult{
+					Text:                result.Text,
+					Format:              result.Format.String(),
+					SymbologyIdentifier: result.SymbologyIdentifier,
+					ECLevel:             result.ECLevel,
+					Version:             result.Version,
+					Orientation:         result.Orientation,
+					ContentType:         result.ContentType.String(),
+					AddOn:               result.AddOn,
+				})
+			}
 		}
 
 		c.JSON(http.StatusOK, DecodeResponse{
@@ -47,7 +47,7 @@ func testEncode(zx zxing.ZXing) {
 	opts := &zxing.EncodeOptions{
 		Width:  256,
 		Height: 256,
-		Format: "QR_CODE",
+		Format: zxing.FormatQRCode,
 	}
 
 	fmt.Printf("编码文本: %s\n", text)
@@ -100,9 +100,7 @@ func testDecode(zx zxing.ZXing) {
 		}
 	}
 
-	opts := &zxing.DecodeOptions{
-		TryHarder: true,
-	}
+	opts := zxing.DefaultDecodeOptions()
 
 	fmt.Printf("解码图像数据: %d bytes, 尺寸: %dx%d\n", len(data), width, height)
 
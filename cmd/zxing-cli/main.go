@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/chennqqi/zxing/pkg/zxing"
@@ -25,9 +26,28 @@ func main() {
 	var (
 		imagePath   = flag.String("i", "", "Image file path to decode")
 		imageDir    = flag.String("d", "", "Directory containing images to decode (batch mode)")
-		backend     = flag.String("backend", "auto", "Backend to use: auto, cgo, wasm")
+		backend     = flag.String("backend", "auto", "Backend to use: auto, cgo, wasm (default $ZXING_BACKEND, then auto)")
 		tryHarder   = flag.Bool("try-harder", false, "Try harder to decode")
-		formats     = flag.String("formats", "all", "Comma-separated list of formats (QR_CODE, CODE_128, etc.) or 'all'")
+		tryRotate   = flag.Bool("try-rotate", true, "Also try rotated images")
+		tryInvert   = flag.Bool("try-invert", false, "Also try inverted images (light codes on dark backgrounds)")
+		tryDown     = flag.Bool("try-downscale", true, "Also try downscaled images")
+		pure        = flag.Bool("pure", false, "Image contains a single aligned barcode (generated images)")
+		binarizer   = flag.String("binarizer", "LocalAverage", "Binarizer: LocalAverage, GlobalHistogram, FixedThreshold, BoolCast")
+		minLines    = flag.Int("min-line-count", 0, "Minimum matching scan lines for linear barcodes (0 = default)")
+		multi       = flag.Bool("multi", false, "Decode all barcodes in each image")
+		maxSymbols  = flag.Int("max-symbols", 0, "Maximum number of barcodes to return in multi mode (0 = unlimited)")
+		textMode    = flag.String("text-mode", "HRI", "Text output mode: HRI, Plain, ECI, Hex, Escaped")
+		eanAddOn    = flag.String("ean-add-on", "Ignore", "EAN-2/EAN-5 add-on handling: Ignore, Read, Require")
+		code39Ext   = flag.Bool("code39-extended", true, "Interpret Code 39 as Full ASCII (extended) when possible")
+		code39Check = flag.Bool("code39-check", false, "Require a valid Code 39 mod 43 check digit")
+		itfCheck    = flag.Bool("itf-check", false, "Require a valid ITF mod 10 check digit")
+		itfLength   = flag.String("itf-length", "", "Allowed ITF length, e.g. 14 or 6-14 (default: any)")
+		codabarSS   = flag.Bool("codabar-start-stop", true, "Keep Codabar start/stop characters in the text")
+		charset     = flag.String("charset", "", "Character set for codes without ECI, e.g. Shift_JIS, GB18030 (default: auto-detect)")
+		returnErrs  = flag.Bool("return-errors", false, "Also report barcodes that were found but could not be decoded")
+		region      = flag.String("region", "", "Only decode inside X,Y,WIDTH,HEIGHT of the image (default: whole image)")
+		formats     = flag.String("formats", "all", "Comma-separated list of formats (QR_CODE, MICRO_QR_CODE, RMQR_CODE, CODE_128, DATABAR, DATABAR_EXPANDED, DX_FILM_EDGE, etc.) or 'all'")
+		cacheDir    = flag.String("cache-dir", os.Getenv("ZXING_COMPILATION_CACHE_DIR"), "Directory caching the compiled WASM module between runs (default $ZXING_COMPILATION_CACHE_DIR, empty disables the cache)")
 		outputJSON  = flag.Bool("json", false, "Output results in JSON format")
 		showVersion = flag.Bool("version", false, "Show version information")
 		showHelp    = flag.Bool("help", false, "Show help information")
@@ -43,6 +63,16 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -d ./images --try-harder\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -i image.png --backend cgo --formats QR_CODE\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -d ./images --json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i labels.png --multi --max-symbols 10\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i produce.png --formats DATABAR,DATABAR_EXPANDED\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i dark-label.png --try-invert\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -d ./generated --pure --binarizer FixedThreshold\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i station.png --multi --return-errors\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i gs1-label.png --text-mode Escaped\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -d ./supplier-jp --charset Shift_JIS\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i magazine.png --ean-add-on Read\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i carton.png --formats ITF --itf-length 14 --itf-check\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i form.png --region 1200,80,400,400\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -64,12 +94,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 创建配置
-	config := &zxing.Config{
-		Backend:  parseBackend(*backend),
-		WASMPath: "wasm/zxingwrapper.wasm",
-		Debug:    false,
-	}
+	// 创建配置：以 ZXING_* 环境变量为基础，命令行显式指定的 -backend 优先
+	config := zxing.LoadConfigFromEnv()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "backend" {
+			config.Backend = parseBackend(*backend)
+		}
+	})
+	config.CompilationCacheDir = *cacheDir
 
 	// 创建ZXing实例
 	zx, err := zxing.New(config)
@@ -79,19 +111,79 @@ func main() {
 	defer zx.Close()
 
 	// 解析格式
-	formatList := parseFormats(*formats)
+	formatList, err := parseFormats(*formats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	bin, err := zxing.ParseBinarizer(*binarizer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	mode, err := zxing.ParseTextMode(*textMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	addOnMode, err := zxing.ParseEANAddOn(*eanAddOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	itfMin, itfMax, err := parseLengthRange(*itfLength)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	roi, err := parseRegion(*region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
 
 	// 创建解码选项
 	decodeOpts := &zxing.DecodeOptions{
 		TryHarder:       *tryHarder,
+		TryRotate:       tryRotate,
+		TryInvert:       *tryInvert,
+		TryDownscale:    tryDown,
+		Binarizer:       bin,
+		IsPure:          *pure,
+		MinLineCount:    *minLines,
 		PossibleFormats: formatList,
+		MaxSymbols:      *maxSymbols,
+		ReturnErrors:    *returnErrs,
+		TextMode:        mode,
+		CharacterSet:    *charset,
+		EANAddOn:        addOnMode,
+		Region:          roi,
+		Symbology: &zxing.SymbologyOptions{
+			Code39ExtendedMode: code39Ext,
+			Code39CheckDigit:   *code39Check,
+			ITFCheckDigit:      *itfCheck,
+			ITFMinLength:       itfMin,
+			ITFMaxLength:       itfMax,
+			CodabarStartStop:   codabarSS,
+		},
 	}
 
 	// 处理单个文件或目录
 	if *imagePath != "" {
-		processImage(zx, *imagePath, decodeOpts, *outputJSON)
+		processImage(zx, *imagePath, decodeOpts, *multi, *outputJSON)
 	} else if *imageDir != "" {
-		processDirectory(zx, *imageDir, decodeOpts, *outputJSON)
+		processDirectory(zx, *imageDir, decodeOpts, *multi, *outputJSON)
 	}
 }
 
@@ -109,23 +201,54 @@ func parseBackend(backend string) zxing.Backend {
 	}
 }
 
-func parseFormats(formats string) []string {
-	if formats == "all" || formats == "" {
-		return []string{"ALL"}
+func parseFormats(formats string) (zxing.Format, error) {
+	if strings.EqualFold(formats, "all") || formats == "" {
+		return zxing.FormatAll, nil
 	}
+	return zxing.ParseFormats(formats)
+}
 
-	parts := strings.Split(formats, ",")
-	result := make([]string, 0, len(parts))
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part != "" {
-			result = append(result, strings.ToUpper(part))
+// parseLengthRange parses "N" or "MIN-MAX"; an empty string means no restriction.
+func parseLengthRange(s string) (int, int, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	lo, hi, found := strings.Cut(s, "-")
+	if !found {
+		hi = lo
+	}
+	minLen, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	maxLen, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || minLen <= 0 || maxLen < minLen {
+		return 0, 0, fmt.Errorf("invalid length range %q", s)
+	}
+	return minLen, maxLen, nil
+}
+
+// parseRegion parses "X,Y,WIDTH,HEIGHT"; an empty string selects the whole image.
+func parseRegion(s string) (image.Rectangle, error) {
+	if s == "" {
+		return image.Rectangle{}, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("invalid region %q: want X,Y,WIDTH,HEIGHT", s)
+	}
+	var v [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("invalid region %q: want X,Y,WIDTH,HEIGHT", s)
 		}
+		v[i] = n
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("invalid region %q: width and height must be positive", s)
 	}
-	return result
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
 }
 
-func processImage(zx zxing.ZXing, imagePath string, opts *zxing.DecodeOptions, jsonOutput bool) {
+func processImage(zx zxing.ZXing, imagePath string, opts *zxing.DecodeOptions, multi, jsonOutput bool) {
 	// 检查文件是否存在
 	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
 		log.Fatalf("Image file not found: %s", imagePath)
@@ -138,7 +261,7 @@ func processImage(zx zxing.ZXing, imagePath string, opts *zxing.DecodeOptions, j
 	}
 
 	// 解码
-	result, err := zx.DecodeImage(context.Background(), img, opts)
+	results, err := decodeImage(zx, img, opts, multi)
 	if err != nil {
 		if jsonOutput {
 			fmt.Printf(`{"success":false,"error":"%s","file":"%s"}`+"\n", err.Error(), imagePath)
@@ -149,14 +272,16 @@ func processImage(zx zxing.ZXing, imagePath string, opts *zxing.DecodeOptions, j
 	}
 
 	// 输出结果
-	if jsonOutput {
-		outputJSONResult(imagePath, result)
-	} else {
-		outputTextResult(imagePath, result)
+	for _, result := range results {
+		if jsonOutput {
+			outputJSONResult(imagePath, result)
+		} else {
+			outputTextResult(imagePath, result)
+		}
 	}
 }
 
-func processDirectory(zx zxing.ZXing, dirPath string, opts *zxing.DecodeOptions, jsonOutput bool) {
+func processDirectory(zx zxing.ZXing, dirPath string, opts *zxing.DecodeOptions, multi, jsonOutput bool) {
 	// 检查目录是否存在
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		log.Fatalf("Directory not found: %s", dirPath)
@@ -197,7 +322,7 @@ func processDirectory(zx zxing.ZXing, dirPath string, opts *zxing.DecodeOptions,
 		}
 
 		// 解码
-		result, err := zx.DecodeImage(context.Background(), img, opts)
+		results, err := decodeImage(zx, img, opts, multi)
 		if err != nil {
 			if jsonOutput {
 				fmt.Printf(`{"success":false,"error":"%s","file":"%s"}`+"\n", err.Error(), imagePath)
@@ -209,10 +334,12 @@ func processDirectory(zx zxing.ZXing, dirPath string, opts *zxing.DecodeOptions,
 		}
 
 		// 输出结果
-		if jsonOutput {
-			outputJSONResult(imagePath, result)
-		} else {
-			outputTextResult(imagePath, result)
+		for _, result := range results {
+			if jsonOutput {
+				outputJSONResult(imagePath, result)
+			} else {
+				outputTextResult(imagePath, result)
+			}
 		}
 		successCount++
 	}
@@ -225,6 +352,18 @@ func processDirectory(zx zxing.ZXing, dirPath string, opts *zxing.DecodeOptions,
 	}
 }
 
+// decodeImage decodes one barcode, or every barcode when multi is set.
+func decodeImage(zx zxing.ZXing, img image.Image, opts *zxing.DecodeOptions, multi bool) ([]*zxing.Result, error) {
+	if multi {
+		return zx.DecodeMultiImage(context.Background(), img, opts)
+	}
+	result, err := zx.DecodeImage(context.Background(), img, opts)
+	if err != nil {
+		return nil, err
+	}
+	return []*zxing.Result{result}, nil
+}
+
 func loadImage(path string) (image.Image, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -252,7 +391,11 @@ func isImageExtension(ext string) bool {
 
 func outputTextResult(imagePath string, result *zxing.Result) {
 	fmt.Printf("📷 File: %s\n", imagePath)
-	fmt.Printf("✅ Decoded successfully!\n")
+	if result.Error != nil {
+		fmt.Printf("⚠️  Barcode found but damaged: %v\n", result.Error)
+	} else {
+		fmt.Printf("✅ Decoded successfully!\n")
+	}
 
 	// 显示后端信息
 	if backend, ok := result.Metadata["backend"].(string); ok {
@@ -261,7 +404,28 @@ func outputTextResult(imagePath string, result *zxing.Result) {
 
 	fmt.Printf("   Text: %s\n", result.Text)
 	fmt.Printf("   Format: %s\n", result.Format)
-	if len(result.Points) > 0 {
+	if result.SymbologyIdentifier != "" {
+		fmt.Printf("   Symbology: %s\n", result.SymbologyIdentifier)
+	}
+	if result.ECLevel != "" {
+		fmt.Printf("   EC Level: %s\n", result.ECLevel)
+	}
+	if result.Version != "" {
+		fmt.Printf("   Version: %s\n", result.Version)
+	}
+	fmt.Printf("   Content: %s\n", result.ContentType)
+	if result.AddOn != "" {
+		fmt.Printf("   Add-On: %s\n", result.AddOn)
+	}
+	if result.StructuredAppend != nil {
+		fmt.Printf("   Sequence: %s\n", result.StructuredAppend)
+	}
+	if result.Orientation != 0 {
+		fmt.Printf("   Orientation: %d°\n", result.Orientation)
+	}
+	if result.Error != nil && len(result.Points) > 0 {
+		fmt.Printf("   Position: %v\n", result.Points)
+	} else if len(result.Points) > 0 {
 		fmt.Printf("   Points: %d\n", len(result.Points))
 	}
 	if len(result.Metadata) > 0 {
@@ -280,6 +444,11 @@ func outputTextResult(imagePath string, result *zxing.Result) {
 }
 
 func outputJSONResult(imagePath string, result *zxing.Result) {
+	if result.Error != nil {
+		fmt.Printf(`{"success":false,"file":"%s","format":"%s","points":%d,"error":%q}`+"\n",
+			imagePath, result.Format, len(result.Points), result.Error.Error())
+		return
+	}
 	fmt.Printf(`{"success":true,"file":"%s","text":"%s","format":"%s","points":%d}`+"\n",
 		imagePath, result.Text, result.Format, len(result.Points))
 }
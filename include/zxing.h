@@ -5,6 +5,10 @@
 extern "C" {
 #endif
 
+// C 接口版本。结构体布局或函数签名变化时递增，并重新编译 lib/ 和 wasm/ 下的预编译库，
+// 这样 Go 端加载旧库时会报错，而不是按新布局读写旧库的结构体
+#define ZXING_ABI_VERSION 2
+
 // 条码格式枚举
 typedef enum {
     FORMAT_NONE = 0,
@@ -22,9 +26,69 @@ typedef enum {
     FORMAT_PDF_417 = 2048,
     FORMAT_UPC_A = 4096,
     FORMAT_UPC_E = 8192,
-    FORMAT_ALL = 0xFFFF
+    FORMAT_DATABAR = 16384,
+    FORMAT_DATABAR_EXPANDED = 32768,
+    FORMAT_DATABAR_LIMITED = 65536,
+    FORMAT_MICRO_QR_CODE = 131072,
+    FORMAT_RMQR_CODE = 262144,
+    FORMAT_DX_FILM_EDGE = 524288,
+    FORMAT_ALL = 0xFFFFF
 } BarcodeFormat;
 
+// 内容类型枚举（与 ZXing::ContentType 一一对应）
+typedef enum {
+    CONTENT_TEXT = 0,
+    CONTENT_BINARY = 1,
+    CONTENT_MIXED = 2,
+    CONTENT_GS1 = 3,
+    CONTENT_ISO15434 = 4,
+    CONTENT_UNKNOWN_ECI = 5
+} ContentType;
+
+// 错误码枚举，通过 ZXingError.code 或 get_last_error_code 返回
+// （使用 ZXING_ 前缀以避免与 Windows 的 ERROR_* 宏冲突）
+typedef enum {
+    ZXING_ERROR_NONE = 0,
+    ZXING_ERROR_NOT_FOUND = 1,     // 未找到条码
+    ZXING_ERROR_CHECKSUM = 2,      // 找到条码但校验失败
+    ZXING_ERROR_FORMAT = 3,        // 找到条码但数据格式错误
+    ZXING_ERROR_UNSUPPORTED = 4,   // 不支持的格式或功能
+    ZXING_ERROR_INVALID_INPUT = 5, // 参数或输入数据无效
+    ZXING_ERROR_INTERNAL = 6       // 内存分配失败等内部错误
+} ZXingErrorCode;
+
+// 单次调用的错误信息。可能失败的函数都以一个 ZXingError* 作为最后一个参数：
+// 调用开始时重置为 ZXING_ERROR_NONE，失败时写入错误码和错误信息；传 NULL 表示不关心。
+// 与 get_last_error 不同，它由调用方持有，并发调用之间互不影响。
+typedef struct {
+    int code;          // 错误码（ZXingErrorCode）
+    char message[256]; // 错误信息（NUL 结尾，过长时截断）
+} ZXingError;
+
+// 二值化算法枚举（与 ZXing::Binarizer 一一对应）
+typedef enum {
+    BINARIZER_LOCAL_AVERAGE = 0,  // 局部均值（默认）
+    BINARIZER_GLOBAL_HISTOGRAM = 1, // 全局直方图
+    BINARIZER_FIXED_THRESHOLD = 2, // 固定阈值 127
+    BINARIZER_BOOL_CAST = 3       // 阈值 0，最快
+} BinarizerType;
+
+// 文本输出模式枚举（TEXT_MODE_HRI 为 zxing-cpp 默认值，故取 0）
+typedef enum {
+    TEXT_MODE_HRI = 0,     // 人类可读形式，如 GS1 的 "(01)..."（默认）
+    TEXT_MODE_PLAIN = 1,   // 按 ECI 或推测的字符集转码的原始内容
+    TEXT_MODE_ECI = 2,     // 遵循 ECI 协议的内容，包含 ECI 标记
+    TEXT_MODE_HEX = 3,     // 原始字节的十六进制表示
+    TEXT_MODE_ESCAPED = 4  // 控制字符转义为 <GS> 等形式
+} TextModeType;
+
+// EAN/UPC 附加码（EAN-2/EAN-5）处理方式枚举（与 ZXing::EanAddOnSymbol 一一对应）
+typedef enum {
+    EAN_ADD_ON_IGNORE = 0,  // 忽略附加码（默认）
+    EAN_ADD_ON_READ = 1,    // 存在时读取附加码
+    EAN_ADD_ON_REQUIRE = 2  // 要求必须有附加码
+} EanAddOnType;
+
 // 解码选项结构体
 typedef struct {
     int formats;      // 要识别的条码格式
@@ -32,6 +96,22 @@ typedef struct {
     int try_rotate;   // 是否尝试旋转图像
     int try_invert;   // 是否尝试反转图像
     int try_downscale; // 是否尝试缩小图像
+    int binarizer;    // 二值化算法（BinarizerType）
+    int downscale_threshold; // 开始缩小的图像尺寸阈值（0 使用库默认值）
+    int downscale_factor;    // 缩小倍数 2-4（0 使用库默认值）
+    int is_pure;      // 图像仅包含一个对齐的条码（程序生成的图像）
+    int min_line_count; // 线性条码需要一致的最少扫描线数（0 使用库默认值）
+    int max_number_of_symbols; // 多码识别时最多查找的条码数（0 表示不限制）
+    int return_errors; // 同时返回已定位但解码失败的条码
+    int text_mode;    // 结果文本的输出模式（TextModeType）
+    int character_set; // 未声明 ECI 时使用的字符集（ZXing::CharacterSet，0 表示自动检测）
+    int ean_add_on;   // EAN/UPC 附加码处理方式（EanAddOnType）
+    int code39_extended;    // 识别 Code 39 Full ASCII（扩展）模式（默认开启）
+    int code39_check_digit; // 要求 Code 39 带有正确的 mod 43 校验位
+    int itf_check_digit;    // 要求 ITF 带有正确的 mod 10 校验位
+    int itf_min_length;     // ITF 最小位数（0 表示不限制）
+    int itf_max_length;     // ITF 最大位数（0 表示不限制）
+    int codabar_start_stop; // 返回 Codabar 起止符（默认开启）
 } DecodeOptions;
 
 // 解码结果结构体
@@ -39,23 +119,82 @@ typedef struct {
     char* text;       // 解码文本
     int format;       // 条码格式
     float confidence; // 置信度
+    int x1, y1;       // 左上角坐标
+    int x2, y2;       // 右上角坐标
+    int x3, y3;       // 右下角坐标
+    int x4, y4;       // 左下角坐标
+    char* symbology_identifier; // 符号标识符（如 "]Q1"）
+    char* ec_level;   // 纠错级别（不适用时为空字符串）
+    char* version;    // 符号版本（不适用时为空字符串）
+    int orientation;  // 方向（角度）
+    int is_mirrored;  // 是否镜像
+    int is_inverted;  // 是否反色
+    int line_count;   // 检测到的扫描线数量（仅线性条码）
+    int content_type; // 内容类型（ContentType）
+    int has_eci;      // 是否包含 ECI
+    int text_length;  // 文本长度（字节，不含结尾的 NUL）
+    unsigned char* bytes;     // 原始数据
+    int bytes_length;         // 原始数据长度
+    unsigned char* bytes_eci; // 遵循 ECI 协议的数据（含符号标识符前缀）
+    int bytes_eci_length;     // ECI 数据长度
+    int sequence_size;        // 结构化追加序列中的条码总数（-1 表示不属于序列，0 表示未知）
+    int sequence_index;       // 在结构化追加序列中的索引（从 0 开始）
+    char* sequence_id;        // 结构化追加序列标识
+    int error_type;           // 解码失败原因（ZXingErrorCode，成功时为 ZXING_ERROR_NONE）
+    char* error_message;      // 解码失败的详细信息（成功时为空字符串）
+    char* ean_add_on;         // EAN-2/EAN-5 附加码内容（无附加码时为空字符串）
 } DecodeResult;
 
+// 编码选项结构体
+typedef struct {
+    int format;         // 条码格式（单个 FORMAT_* 值）
+    char ec_level[16];  // 纠错级别（空字符串表示使用默认值）
+} EncodeOptions;
+
+// 编码结果：条码模块矩阵，每个模块一个字节（1 为深色，0 为浅色），不含静区
+typedef struct {
+    unsigned char* modules; // 模块数据，按行存储
+    int width;              // 每行模块数
+    int height;             // 模块行数
+} EncodeResult;
+
 // 创建默认解码选项
 DecodeOptions* create_default_options();
 
-// Configures all fields of an existing decode options structure.
+// 设置解码选项的格式和基本开关
 void configure_decode_options(DecodeOptions* options, int formats, int try_harder,
                               int try_rotate, int try_invert, int try_downscale);
 
+// 设置解码选项的调优字段。取值越界时返回 -1 并填写 error，成功返回 0
+int configure_decode_tuning(DecodeOptions* options, int binarizer, int downscale_threshold,
+                            int downscale_factor, int is_pure, int min_line_count,
+                            int max_number_of_symbols, int return_errors, ZXingError* error);
+
+// 设置结果文本的输出模式（TextModeType）。未知模式返回 -1 并填写 error，成功返回 0
+int configure_decode_text_mode(DecodeOptions* options, int text_mode, ZXingError* error);
+
+// 设置未声明 ECI 时使用的字符集（如 "Shift_JIS"、"GB18030"），NULL 或空字符串恢复自动检测。
+// 未知名称返回 -1 并填写 error，成功返回 0
+int configure_decode_charset(DecodeOptions* options, const char* charset, ZXingError* error);
+
+// 设置 EAN-2/EAN-5 附加码处理方式（EanAddOnType）。未知取值返回 -1 并填写 error，成功返回 0
+int configure_decode_ean_add_on(DecodeOptions* options, int ean_add_on, ZXingError* error);
+
+// 设置 Code 39、ITF 和 Codabar 的专用选项，不满足校验位或长度要求的条码视为未找到。
+// 长度无效时返回 -1 并填写 error，成功返回 0
+int configure_decode_symbology(DecodeOptions* options, int code39_extended, int code39_check_digit,
+                               int itf_check_digit, int itf_min_length, int itf_max_length,
+                               int codabar_start_stop, ZXingError* error);
+
 // 释放解码选项
 void free_options(DecodeOptions* options);
 
 // 解码单个条码
-DecodeResult* decode_barcode(const char* image_path, const DecodeOptions* options);
+DecodeResult* decode_barcode(const char* image_path, const DecodeOptions* options, ZXingError* error);
 
 // 解码多个条码
-DecodeResult** decode_barcodes(const char* image_path, const DecodeOptions* options, int* count);
+DecodeResult** decode_barcodes(const char* image_path, const DecodeOptions* options, int* count,
+                               ZXingError* error);
 
 // 获取解码结果
 DecodeResult* decode_result_get(DecodeResult** results, int index);
@@ -66,16 +205,44 @@ void free_result(DecodeResult* result);
 // 释放多个解码结果
 void free_results(DecodeResult** results, int count);
 
-// 获取错误信息
+// 获取当前线程最近一次错误的错误信息。
+// 仅在调用和读取位于同一线程时可靠（如单线程的 WASM 模块），并发调用方应使用 error 参数
 const char* get_last_error();
 
-// Decode barcode from raw image file data (PNG/JPEG/BMP etc.)
-// Used by wazero runtime which cannot access filesystem
-DecodeResult* decode_barcode_data(const unsigned char* file_data, int file_size, const DecodeOptions* options);
+// 获取当前线程最近一次错误的错误码（ZXingErrorCode），限制同 get_last_error
+int get_last_error_code();
+
+// 返回编译该库时的 ZXING_ABI_VERSION
+int zxing_abi_version();
+
+// 从内存中的图像文件数据（PNG/JPEG/BMP 等）解码单个条码，供无法访问文件系统的 WASM 运行时使用
+DecodeResult* decode_barcode_data(const unsigned char* file_data, int file_size, const DecodeOptions* options,
+                                  ZXingError* error);
 
-// Decodes tightly packed raw pixels without an intermediate encoded image.
+// 直接解码紧密排列的原始像素中的单个条码
 DecodeResult* decode_barcode_pixels(const unsigned char* data, int width, int height,
-                                    int channels, const DecodeOptions* options);
+                                    int channels, const DecodeOptions* options, ZXingError* error);
+
+// 解码紧密排列的原始像素中的所有条码。返回的数组包含 *count 个结果，需用 free_results 释放
+DecodeResult** decode_barcodes_pixels(const unsigned char* data, int width, int height,
+                                      int channels, const DecodeOptions* options, int* count,
+                                      ZXingError* error);
+
+// 创建默认编码选项（QR Code，默认纠错级别）
+EncodeOptions* create_encode_options();
+
+// 设置编码选项，ec_level 可为 NULL。ec_level 过长时返回 -1，成功返回 0
+int configure_encode_options(EncodeOptions* options, int format, const char* ec_level, ZXingError* error);
+
+// 释放编码选项
+void free_encode_options(EncodeOptions* options);
+
+// 将文本编码为条码模块矩阵，失败时返回 NULL 并填写 error
+EncodeResult* encode_barcode(const char* text, int text_length, const EncodeOptions* options,
+                             ZXingError* error);
+
+// 释放编码结果
+void free_encode_result(EncodeResult* result);
 
 #ifdef __cplusplus
 }
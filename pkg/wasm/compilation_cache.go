@@ -0,0 +1,85 @@
+//go:build (!cgo || !(linux || windows)) && !(js && wasm)
+
+package wasm
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// compilationCaches holds one file-backed compilation cache per directory,
+// shared by every Runtime in the process. Besides the files on disk, a shared
+// cache keeps compiled modules in memory, so only the first Runtime of a
+// process compiles or loads the module.
+var compilationCaches = struct {
+	sync.Mutex
+	byDir map[string]wazero.CompilationCache
+}{byDir: make(map[string]wazero.CompilationCache)}
+
+// sharedCompilationCache returns the process-wide compilation cache for dir,
+// creating dir if needed.
+func sharedCompilationCache(dir string) (wazero.CompilationCache, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	compilationCaches.Lock()
+	defer compilationCaches.Unlock()
+	if cache, ok := compilationCaches.byDir[dir]; ok {
+		return cache, nil
+	}
+	cache, err := wazero.NewCompilationCacheWithDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	compilationCaches.byDir[dir] = cache
+	return cache, nil
+}
+
+// resetCompilationCache discards the cached modules in dir after one of them
+// failed to load. The next sharedCompilationCache call starts from an empty
+// cache. The previous cache is not closed, since runtimes created from it
+// may still run modules it compiled.
+func resetCompilationCache(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	compilationCaches.Lock()
+	defer compilationCaches.Unlock()
+	delete(compilationCaches.byDir, dir)
+
+	// wazero keeps its files in a wazero-<version>-<arch>-<os> subdirectory;
+	// only the running version's is removed, so other platforms and wazero
+	// versions sharing dir keep their caches.
+	return os.RemoveAll(filepath.Join(dir, wazeroCacheSubdir()))
+}
+
+// wazeroCacheSubdir returns the subdirectory the running wazero version writes
+// its compilation cache to, resolving the version the way wazero's
+// internal/version.GetWazeroVersion does.
+func wazeroCacheSubdir() string {
+	version := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if strings.Contains(dep.Path, "github.com/tetratelabs/wazero") {
+				version = dep.Version
+			}
+		}
+		if version == "" || version == "(devel)" {
+			version = info.Main.Version
+		}
+	}
+	if version == "" || version == "(devel)" {
+		version = "dev"
+	}
+	return "wazero-" + version + "-" + runtime.GOARCH + "-" + runtime.GOOS
+}
@@ -7,23 +7,47 @@ package wasm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"strings"
 	"syscall/js"
 )
 
+// ErrUnsupported 加载的 WASM 模块未导出所需函数
+var ErrUnsupported = errors.New("function not exported by the WASM module")
+
 // Runtime WASM 运行时管理器
 type Runtime struct {
 	module js.Value
 	ready  bool
 }
 
-// DecodeResult 解码结果
+// DecodeResult 解码结果，字段与 wasm/wrapper.cpp 输出的 JS 对象一一对应
 type DecodeResult struct {
 	Success      bool   `json:"success"`
 	Text         string `json:"text"`
 	Format       string `json:"format"`
 	ErrorCode    int    `json:"error_code"`
 	ErrorMessage string `json:"error_message"`
+	// Points 条码四个角点：左上、右上、右下、左下
+	Points []image.Point `json:"points"`
+
+	SymbologyIdentifier string `json:"symbology_identifier"`
+	ECLevel             string `json:"ec_level"`
+	Version             string `json:"version"`
+	Orientation         int    `json:"orientation"`
+	IsMirrored          bool   `json:"is_mirrored"`
+	IsInverted          bool   `json:"is_inverted"`
+	LineCount           int    `json:"line_count"`
+	ContentType         int    `json:"content_type"`
+	HasECI              bool   `json:"has_eci"`
+	Bytes               []byte `json:"bytes"`
+	BytesECI            []byte `json:"bytes_eci"`
+	SequenceSize        int    `json:"sequence_size"`
+	SequenceIndex       int    `json:"sequence_index"`
+	SequenceID          string `json:"sequence_id"`
+	AddOn               string `json:"add_on"`
 }
 
 // EncodeResult 编码结果
@@ -43,7 +67,9 @@ func NewRuntime() *Runtime {
 	}
 }
 
-// Initialize 初始化 WASM 模块
+// Initialize 初始化 WASM 模块。
+// 页面需先加载 wasm/build.sh 生成的 zxing.js，它定义 Emscripten 模块工厂 ZXingWASM；
+// wasmPath 为对应 zxing.wasm 的地址
 func (r *Runtime) Initialize(ctx context.Context, wasmPath string) error {
 	if r.ready {
 		return nil
@@ -54,58 +80,53 @@ func (r *Runtime) Initialize(ctx context.Context, wasmPath string) error {
 		return fmt.Errorf("WebAssembly not supported in this environment")
 	}
 
-	// 加载 WASM 模块
-	promise := js.Global().Call("fetch", wasmPath)
-
-	// 等待加载完成
-	done := make(chan error, 1)
+	factory := js.Global().Get("ZXingWASM")
+	if factory.Type() != js.TypeFunction {
+		return fmt.Errorf("ZXingWASM is not defined; load zxing.js built by wasm/build.sh first")
+	}
 
-	promise.Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		response := args[0]
-		if !response.Get("ok").Bool() {
-			done <- fmt.Errorf("failed to fetch WASM module: %s", response.Get("statusText").String())
-			return nil
+	// locateFile 让 Emscripten 从 wasmPath 加载 .wasm 文件
+	locateFile := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) > 0 && strings.HasSuffix(args[0].String(), ".wasm") {
+			return wasmPath
 		}
+		if len(args) > 1 {
+			return args[1].String() + args[0].String()
+		}
+		return args[0].String()
+	})
+	moduleConfig := js.Global().Get("Object").New()
+	moduleConfig.Set("locateFile", locateFile)
 
-		arrayBufferPromise := response.Call("arrayBuffer")
-		arrayBufferPromise.Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			arrayBuffer := args[0]
-
-			instantiatePromise := js.Global().Get("WebAssembly").Call("instantiate", arrayBuffer)
-			instantiatePromise.Call("then", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-				result := args[0]
-				r.module = result.Get("instance")
-				r.ready = true
-				done <- nil
-				return nil
-			})).Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-				done <- fmt.Errorf("failed to instantiate WASM: %v", args[0])
-				return nil
-			}))
-
-			return nil
-		})).Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-			done <- fmt.Errorf("failed to get array buffer: %v", args[0])
-			return nil
-		}))
-
+	// 等待加载完成
+	done := make(chan error, 1)
+	onLoad := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		r.module = args[0]
+		r.ready = true
+		done <- nil
 		return nil
-	})).Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		done <- fmt.Errorf("failed to fetch: %v", args[0])
+	})
+	onError := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- fmt.Errorf("failed to instantiate WASM: %v", args[0])
 		return nil
-	}))
+	})
+	factory.Invoke(moduleConfig).Call("then", onLoad).Call("catch", onError)
 
 	select {
 	case err := <-done:
+		locateFile.Release()
+		onLoad.Release()
+		onError.Release()
 		return err
 	case <-ctx.Done():
+		// 加载仍在进行，回调保留到加载结束
 		return ctx.Err()
 	}
 }
 
-// IsReady 检查运行时是否就绪
+// IsReady 检查运行时是否就绪，nil 运行时视为未就绪
 func (r *Runtime) IsReady() bool {
-	return r.ready
+	return r != nil && r.ready
 }
 
 // DecodeImage 解码图像数据
@@ -119,7 +140,10 @@ func (r *Runtime) DecodeImage(imageData []byte, width, height, channels int) (*D
 	js.CopyBytesToJS(jsArray, imageData)
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("decode_image_data", jsArray, width, height, channels)
+	result, err := r.call("decode_image_data", jsArray, width, height, channels)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -139,7 +163,10 @@ func (r *Runtime) DecodeImageFile(filePath string) (*DecodeResult, error) {
 	}
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("decode_image_file", filePath)
+	result, err := r.call("decode_image_file", filePath)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -163,7 +190,10 @@ func (r *Runtime) DecodeMultiple(imageData []byte, width, height, channels int)
 	js.CopyBytesToJS(jsArray, imageData)
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("decode_multiple_barcodes", jsArray, width, height, channels)
+	result, err := r.call("decode_multiple_barcodes", jsArray, width, height, channels)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -183,7 +213,10 @@ func (r *Runtime) EncodeText(text string, width, height int) (*EncodeResult, err
 	}
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("encode_text_to_qr", text, width, height)
+	result, err := r.call("encode_text_to_qr", text, width, height)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -203,7 +236,10 @@ func (r *Runtime) EncodeTextToBarcode(text, format string, width, height int) (*
 	}
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("encode_text_to_barcode", text, format, width, height)
+	result, err := r.call("encode_text_to_barcode", text, format, width, height)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -223,7 +259,10 @@ func (r *Runtime) GetSupportedFormats() ([]string, error) {
 	}
 
 	// 调用 WASM 函数
-	result := r.module.Get("exports").Call("get_supported_formats")
+	result, err := r.call("get_supported_formats")
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析结果
 	resultJSON := js.Global().Get("JSON").Call("stringify", result).String()
@@ -236,6 +275,15 @@ func (r *Runtime) GetSupportedFormats() ([]string, error) {
 	return formats, nil
 }
 
+// call 调用 WASM 模块导出的函数，模块未导出该函数时返回 ErrUnsupported
+func (r *Runtime) call(name string, args ...interface{}) (js.Value, error) {
+	fn := r.module.Get(name)
+	if fn.Type() != js.TypeFunction {
+		return js.Undefined(), fmt.Errorf("%s: %w", name, ErrUnsupported)
+	}
+	return fn.Invoke(args...), nil
+}
+
 // Close 关闭运行时
 func (r *Runtime) Close() error {
 	r.ready = false
@@ -16,6 +16,12 @@ type DecodeResult struct {
 	Format string
 }
 
+// EncodeOptions 编码选项
+type EncodeOptions struct {
+	Format  int
+	ECLevel string
+}
+
 // EncodeResult 编码结果
 type EncodeResult struct {
 	Data   []byte
@@ -39,7 +45,7 @@ func (r *Runtime) DecodeImage(data []byte, width, height, channels int) (*Decode
 }
 
 // EncodeText 编码文本
-func (r *Runtime) EncodeText(text string, width, height int) (*EncodeResult, error) {
+func (r *Runtime) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (*EncodeResult, error) {
 	return nil, fmt.Errorf("WASM runtime not available in non-WASM environment")
 }
 
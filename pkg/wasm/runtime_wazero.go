@@ -6,7 +6,9 @@ package wasm
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"image"
 	"os"
 	"sync"
 
@@ -15,6 +17,26 @@ import (
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
+// ErrClosed is returned when the runtime has not been initialized or has been closed.
+var ErrClosed = errors.New("WASM runtime not initialized or closed")
+
+// ErrUnsupported is returned when the loaded WASM module does not export a
+// function the call needs, typically because it was built from an older wrapper.
+var ErrUnsupported = errors.New("function not exported by the WASM module")
+
+// GuestError is a failure reported by the WASM module through the ZXingError
+// out-parameter of the failed call.
+type GuestError struct {
+	// Code is the ZXingErrorCode from include/zxing.h.
+	Code    int
+	Message string
+}
+
+func (e *GuestError) Error() string { return e.Message }
+
+// ErrorCode returns the ZXingErrorCode reported with the error.
+func (e *GuestError) ErrorCode() int { return e.Code }
+
 // DecodeOptions controls barcode decoding behavior in the WASM backend.
 type DecodeOptions struct {
 	Formats      int
@@ -22,28 +44,118 @@ type DecodeOptions struct {
 	TryRotate    bool
 	TryInvert    bool
 	TryDownscale bool
+
+	// Reader tuning, applied through configure_decode_tuning.
+	// Zero values keep the zxing-cpp defaults.
+	Binarizer          int
+	DownscaleThreshold int
+	DownscaleFactor    int
+	IsPure             bool
+	MinLineCount       int
+	MaxSymbols         int
+	// ReturnErrors also returns symbols that were located but failed to decode.
+	ReturnErrors bool
+
+	// TextMode is a TextModeType value applied through configure_decode_text_mode;
+	// 0 keeps the default (HRI).
+	TextMode int
+
+	// CharacterSet is the fallback character set for symbols without ECI,
+	// applied through configure_decode_charset; empty keeps auto-detection.
+	CharacterSet string
+
+	// EANAddOn is an EanAddOnType value applied through configure_decode_ean_add_on;
+	// 0 ignores add-on symbols.
+	EANAddOn int
+
+	// Symbology holds the Code 39, ITF and Codabar options, applied through
+	// configure_decode_symbology; nil keeps the module defaults.
+	Symbology *SymbologyOptions
+}
+
+// SymbologyOptions mirrors the symbology fields of the C DecodeOptions struct.
+type SymbologyOptions struct {
+	Code39Extended   bool
+	Code39CheckDigit bool
+	ITFCheckDigit    bool
+	ITFMinLength     int
+	ITFMaxLength     int
+	CodabarStartStop bool
+}
+
+// hasTuning reports whether any reader tuning field differs from its default.
+func (o *DecodeOptions) hasTuning() bool {
+	return o.Binarizer != 0 || o.DownscaleThreshold != 0 || o.DownscaleFactor != 0 ||
+		o.IsPure || o.MinLineCount != 0 || o.MaxSymbols != 0 || o.ReturnErrors
 }
 
 // Runtime manages the wazero WASM runtime for ZXing.
-// A single Runtime instance is not safe for concurrent use;
-// callers must serialize access through the embedded mutex.
+// The module is compiled once and shared by a pool of module instances, so up
+// to Concurrency calls run in parallel; further callers wait in FIFO order
+// until an instance is free or their context is done. A call canceled through
+// its context only discards the instance it ran on, and the next checkout of
+// that slot instantiates a fresh one from the compiled module.
 type Runtime struct {
 	mu       sync.Mutex
 	runtime  wazero.Runtime
-	module   api.Module
 	compiled wazero.CompiledModule
+	size     int
+	pool     chan *instance
+	closing  bool
+	cacheDir string
+}
+
+// instance is one pool slot. Its module is created on first checkout and
+// recreated after it was discarded.
+type instance struct {
+	pool   chan *instance
+	module api.Module
+	// errPtr is the ZXingError passed to every wrapper call of the instance.
+	// An instance runs one call at a time, so one buffer per module suffices.
+	errPtr uint32
 }
 
 // DecodeResult holds the result of a barcode decode operation.
+// When DecodeOptions.ReturnErrors is set, a located symbol that failed to decode
+// is returned with Success true and a non-zero ErrorCode describing the failure.
 type DecodeResult struct {
 	Success      bool   `json:"success"`
 	Text         string `json:"text"`
 	Format       string `json:"format"`
 	ErrorCode    int    `json:"error_code"`
 	ErrorMessage string `json:"error_message"`
+	// Points holds the symbol corners: top-left, top-right, bottom-right, bottom-left.
+	Points []image.Point `json:"points"`
+
+	SymbologyIdentifier string `json:"symbology_identifier"`
+	ECLevel             string `json:"ec_level"`
+	Version             string `json:"version"`
+	Orientation         int    `json:"orientation"`
+	IsMirrored          bool   `json:"is_mirrored"`
+	IsInverted          bool   `json:"is_inverted"`
+	LineCount           int    `json:"line_count"`
+	ContentType         int    `json:"content_type"`
+	HasECI              bool   `json:"has_eci"`
+	Bytes               []byte `json:"bytes"`
+	BytesECI            []byte `json:"bytes_eci"`
+	SequenceSize        int    `json:"sequence_size"`
+	SequenceIndex       int    `json:"sequence_index"`
+	SequenceID          string `json:"sequence_id"`
+	// AddOn is the EAN-2/EAN-5 supplement of an EAN/UPC symbol, empty when absent.
+	AddOn string `json:"add_on"`
+}
+
+// EncodeOptions controls barcode encoding behavior in the WASM backend.
+type EncodeOptions struct {
+	// Format is a single BarcodeFormat enum value; 0 selects QR_CODE.
+	Format int
+	// ECLevel is the error correction level passed to the writer, e.g. "L".."H" for QR Code.
+	ECLevel string
 }
 
 // EncodeResult holds the result of a barcode encode operation.
+// Data is the module matrix in row-major order, one byte per module (1 = dark),
+// and Width/Height are measured in modules without a quiet zone.
 type EncodeResult struct {
 	Success      bool    `json:"success"`
 	Width        int     `json:"width"`
@@ -53,17 +165,35 @@ type EncodeResult struct {
 	ErrorMessage string  `json:"error_message"`
 }
 
-// NewRuntime creates a new wazero WASM runtime instance.
+// NewRuntime creates a wazero WASM runtime with a single module instance.
 func NewRuntime() *Runtime {
-	return &Runtime{}
+	return NewRuntimeWithConcurrency(1)
+}
+
+// NewRuntimeWithConcurrency creates a wazero WASM runtime whose pool holds up to
+// n module instances. Instances are created lazily, so idle slots cost no memory;
+// each instance keeps the linear memory it has grown to. n < 1 is treated as 1.
+func NewRuntimeWithConcurrency(n int) *Runtime {
+	return &Runtime{size: max(n, 1)}
 }
 
-// Initialize loads and instantiates the WASM module from the given file path.
+// SetCompilationCacheDir stores the compiled module in dir, so later runtimes
+// and processes load it instead of compiling it again. The cache is shared by
+// every Runtime of the process that uses the same directory. An empty dir
+// disables the cache. It must be called before Initialize.
+func (r *Runtime) SetCompilationCacheDir(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheDir = dir
+}
+
+// Initialize loads and compiles the WASM module from the given file path and
+// instantiates the first pool instance.
 func (r *Runtime) Initialize(ctx context.Context, wasmPath string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.module != nil && !r.module.IsClosed() {
+	if r.compiled != nil {
 		return nil
 	}
 
@@ -72,43 +202,199 @@ func (r *Runtime) Initialize(ctx context.Context, wasmPath string) error {
 		return fmt.Errorf("failed to read WASM file %s: %w", wasmPath, err)
 	}
 
-	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
-	r.runtime = wazero.NewRuntimeWithConfig(ctx, config)
-
-	// Instantiate WASI host functions (required by Emscripten STANDALONE_WASM)
-	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r.runtime); err != nil {
-		r.runtime.Close(context.Background())
-		r.runtime = nil
-		return fmt.Errorf("failed to instantiate WASI: %w", err)
-	}
-
-	// Compile the WASM binary
-	r.compiled, err = r.runtime.CompileModule(ctx, wasmBytes)
+	r.runtime, r.compiled, err = r.compile(ctx, wasmBytes)
 	if err != nil {
-		r.runtime.Close(context.Background())
-		r.runtime = nil
-		return fmt.Errorf("failed to compile WASM module: %w", err)
+		return err
 	}
 
-	// Instantiate the WASM module
-	// Disable _start to prevent proc_exit from being called automatically
-	r.module, err = r.runtime.InstantiateModule(ctx, r.compiled, wazero.NewModuleConfig().WithStartFunctions())
+	// Instantiate one module up front so a broken or stale binary fails here
+	module, err := r.instantiate(ctx)
+	if err == nil {
+		if err = checkABI(ctx, module); err != nil {
+			module.Close(context.Background())
+		}
+	} else {
+		err = fmt.Errorf("failed to instantiate WASM module: %w", err)
+	}
 	if err != nil {
 		r.compiled.Close(context.Background())
 		r.runtime.Close(context.Background())
 		r.runtime = nil
 		r.compiled = nil
-		return fmt.Errorf("failed to instantiate WASM module: %w", err)
+		return err
 	}
 
+	r.pool = make(chan *instance, max(r.size, 1))
+	r.pool <- &instance{pool: r.pool, module: module}
+	for i := 1; i < cap(r.pool); i++ {
+		r.pool <- &instance{pool: r.pool}
+	}
 	return nil
 }
 
-// IsReady returns whether the runtime has been initialized and the module is still usable.
+// abiVersion is the ZXING_ABI_VERSION of include/zxing.h that the struct
+// readers and call signatures of this file follow.
+const abiVersion = 2
+
+// checkABI reports an error when module was built from a zxing.h with another
+// ZXING_ABI_VERSION, whose structs this runtime would read with the wrong layout.
+func checkABI(ctx context.Context, module api.Module) error {
+	fn := module.ExportedFunction("zxing_abi_version")
+	if fn == nil {
+		return fmt.Errorf("WASM module predates ABI version %d (zxing_abi_version not exported); rebuild it with 'go run ./cmd/build build-wasm'", abiVersion)
+	}
+	res, err := fn.Call(ctx)
+	if err != nil {
+		return fmt.Errorf("WASM zxing_abi_version call failed: %w", err)
+	}
+	if got := int32(res[0]); got != abiVersion {
+		return fmt.Errorf("WASM module has ABI version %d, expected %d; rebuild it with 'go run ./cmd/build build-wasm'", got, abiVersion)
+	}
+	return nil
+}
+
+// compile creates the wazero runtime and compiles wasmBytes, through the
+// shared compilation cache when a cache directory is set. A cache directory
+// that cannot be opened is an error. When compiling through the cache fails
+// but compiling without it succeeds, the cached entry is corrupted and the
+// cache is reset, so a broken cache only costs a recompilation; a cache that
+// cannot be reset is an error as well.
+func (r *Runtime) compile(ctx context.Context, wasmBytes []byte) (wazero.Runtime, wazero.CompiledModule, error) {
+	if r.cacheDir == "" {
+		return compileModule(ctx, nil, wasmBytes)
+	}
+	cache, err := sharedCompilationCache(r.cacheDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open compilation cache %s: %w", r.cacheDir, err)
+	}
+
+	rt, compiled, cacheErr := compileModule(ctx, cache, wasmBytes)
+	if cacheErr == nil || ctx.Err() != nil {
+		return rt, compiled, cacheErr
+	}
+	rt, compiled, err = compileModule(ctx, nil, wasmBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := resetCompilationCache(r.cacheDir); err != nil {
+		rt.Close(context.Background())
+		return nil, nil, fmt.Errorf("failed to reset compilation cache %s after %v: %w", r.cacheDir, cacheErr, err)
+	}
+	return rt, compiled, nil
+}
+
+// compileModule creates a wazero runtime, backed by cache unless it is nil,
+// and compiles wasmBytes in it.
+func compileModule(ctx context.Context, cache wazero.CompilationCache, wasmBytes []byte) (wazero.Runtime, wazero.CompiledModule, error) {
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if cache != nil {
+		config = config.WithCompilationCache(cache)
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, config)
+
+	// Instantiate WASI host functions (required by Emscripten STANDALONE_WASM)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(context.Background())
+		return nil, nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+
+	// Compile the WASM binary
+	compiled, err := rt.CompileModule(ctx, wasmBytes)
+	if err != nil {
+		rt.Close(context.Background())
+		return nil, nil, fmt.Errorf("failed to compile WASM module: %w", err)
+	}
+	return rt, compiled, nil
+}
+
+// IsReady returns whether the runtime has been initialized and not closed.
+// A module instance discarded after a canceled call does not affect readiness.
 func (r *Runtime) IsReady() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.module != nil && !r.module.IsClosed()
+	return r.compiled != nil && !r.closing
+}
+
+// Concurrency returns the maximum number of calls that run in parallel.
+func (r *Runtime) Concurrency() int {
+	return max(r.size, 1)
+}
+
+// instantiate creates a new instance of the compiled module.
+func (r *Runtime) instantiate(ctx context.Context) (api.Module, error) {
+	// Disable _start to prevent proc_exit from being called automatically.
+	// Instances are anonymous so several can coexist in one runtime.
+	config := wazero.NewModuleConfig().WithStartFunctions().WithName("")
+	return r.runtime.InstantiateModule(ctx, r.compiled, config)
+}
+
+// checkout waits for a free pool slot, in the order callers arrived, and
+// returns it with a usable module instance. The caller must release it.
+func (r *Runtime) checkout(ctx context.Context) (*instance, error) {
+	r.mu.Lock()
+	pool := r.pool
+	r.mu.Unlock()
+	if pool == nil {
+		return nil, ErrClosed
+	}
+
+	var in *instance
+	select {
+	case slot, ok := <-pool:
+		if !ok {
+			return nil, ErrClosed
+		}
+		in = slot
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	r.mu.Lock()
+	closing := r.closing
+	r.mu.Unlock()
+	if closing {
+		in.release()
+		return nil, ErrClosed
+	}
+
+	// Close waits for every slot, so the compiled module outlives this call
+	if in.module == nil || in.module.IsClosed() {
+		module, err := r.instantiate(context.WithoutCancel(ctx))
+		if err != nil {
+			in.release()
+			return nil, fmt.Errorf("failed to instantiate WASM module: %w", err)
+		}
+		in.module = module
+		in.errPtr = 0
+	}
+	if in.module.Memory() == nil {
+		in.release()
+		return nil, fmt.Errorf("WASM module has no exported memory")
+	}
+	if in.errPtr == 0 {
+		ptr, err := in.guestMalloc(context.WithoutCancel(ctx), zxingErrorSize)
+		if err != nil {
+			in.release()
+			return nil, err
+		}
+		in.errPtr = ptr
+	}
+	return in, nil
+}
+
+// release returns the slot to the pool.
+func (in *instance) release() {
+	in.pool <- in
+}
+
+// discard closes the module instance after its call was canceled or trapped.
+// Either way the guest stopped mid-call, so its heap cannot be trusted afterwards.
+// The closed instance stays in place so deferred cleanup calls fail harmlessly;
+// the next checkout of the slot replaces it.
+func (in *instance) discard() {
+	if in.module != nil {
+		in.module.Close(context.Background())
+	}
 }
 
 // DecodeImage decodes raw pixel data using the WASM module's decode_barcode_pixels export.
@@ -119,89 +405,264 @@ func (r *Runtime) DecodeImage(ctx context.Context, data []byte, width, height, c
 		return nil, err
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	if r.module == nil || r.module.IsClosed() {
-		return nil, fmt.Errorf("WASM runtime not initialized or closed")
+	in, err := r.checkout(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer in.release()
+	mem := in.module.Memory()
 
-	mem := r.module.Memory()
-	if mem == nil {
-		return nil, fmt.Errorf("WASM module has no exported memory")
+	// Only the decode call observes cancellation; setup and cleanup must not
+	// close the instance halfway through.
+	hctx := context.WithoutCancel(ctx)
+
+	pixelPtr, optsPtr, release, err := in.prepareDecode(hctx, mem, data, width, height, channels, opts)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// Allocate guest memory for pixel data
-	pixelSize := width * height * channels
-	pixelPtr, err := r.guestMalloc(ctx, uint64(pixelSize))
+	// Call decode_barcode_pixels(data, width, height, channels, options, error)
+	decodeFn := in.module.ExportedFunction("decode_barcode_pixels")
+	if decodeFn == nil {
+		return nil, notExported("decode_barcode_pixels")
+	}
+
+	resultRes, err := decodeFn.Call(ctx, uint64(pixelPtr), uint64(width), uint64(height), uint64(channels), uint64(optsPtr), uint64(in.errPtr))
 	if err != nil {
+		in.discard()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("WASM decode_barcode_pixels call canceled: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("WASM decode_barcode_pixels call failed: %w", err)
+	}
+	resultPtr := uint32(resultRes[0])
+
+	if resultPtr == 0 {
+		return nil, fmt.Errorf("WASM decode failed: %w", in.guestError(mem))
+	}
+	defer in.guestFreeResult(hctx, resultPtr)
+
+	return readDecodeResult(mem, resultPtr)
+}
+
+// DecodeMultiImage decodes every barcode in raw pixel data using the WASM module's
+// decode_barcodes_pixels export. Pixel layout requirements are the same as DecodeImage.
+func (r *Runtime) DecodeMultiImage(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) ([]*DecodeResult, error) {
+	if err := validateDecodeInput(data, width, height, channels); err != nil {
 		return nil, err
 	}
-	defer r.guestFree(ctx, pixelPtr)
 
-	// Write pixel data into guest memory
-	if !mem.Write(pixelPtr, data[:pixelSize]) {
-		return nil, fmt.Errorf("failed to write pixel data to WASM memory")
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Allocate and configure decode options
-	optsPtr, err := r.guestCreateOptions(ctx)
+	in, err := r.checkout(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer r.guestFreeOptions(ctx, optsPtr)
+	defer in.release()
+	mem := in.module.Memory()
+
+	// Only the decode call observes cancellation; setup and cleanup must not
+	// close the instance halfway through.
+	hctx := context.WithoutCancel(ctx)
 
-	if err := r.guestConfigureOptions(ctx, optsPtr, opts); err != nil {
+	pixelPtr, optsPtr, release, err := in.prepareDecode(hctx, mem, data, width, height, channels, opts)
+	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	// Call decode_barcode_pixels(data, width, height, channels, options)
-	decodeFn := r.module.ExportedFunction("decode_barcode_pixels")
+	// The result count is written by the guest into a 4-byte out parameter
+	countPtr, err := in.guestMalloc(hctx, 4)
+	if err != nil {
+		return nil, err
+	}
+	defer in.guestFree(hctx, countPtr)
+
+	// Call decode_barcodes_pixels(data, width, height, channels, options, count, error)
+	decodeFn := in.module.ExportedFunction("decode_barcodes_pixels")
 	if decodeFn == nil {
-		return nil, fmt.Errorf("decode_barcode_pixels not exported in WASM module")
+		return nil, notExported("decode_barcodes_pixels")
 	}
 
-	resultRes, err := decodeFn.Call(ctx, uint64(pixelPtr), uint64(width), uint64(height), uint64(channels), uint64(optsPtr))
+	resultRes, err := decodeFn.Call(ctx, uint64(pixelPtr), uint64(width), uint64(height), uint64(channels), uint64(optsPtr), uint64(countPtr), uint64(in.errPtr))
 	if err != nil {
-		// Cancellation may close the module; mark it unusable
-		if ctx.Err() != nil {
-			r.module = nil
+		in.discard()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("WASM decode_barcodes_pixels call canceled: %w", ctxErr)
 		}
-		return nil, fmt.Errorf("WASM decode_barcode_pixels call failed: %w", err)
+		return nil, fmt.Errorf("WASM decode_barcodes_pixels call failed: %w", err)
 	}
-	resultPtr := uint32(resultRes[0])
+	arrayPtr := uint32(resultRes[0])
 
-	if resultPtr == 0 {
-		errMsg := r.guestLastError(ctx, mem)
-		return nil, fmt.Errorf("WASM decode failed: %s", errMsg)
+	if arrayPtr == 0 {
+		return nil, fmt.Errorf("WASM decode failed: %w", in.guestError(mem))
 	}
-	defer r.guestFreeResult(ctx, resultPtr)
 
-	// Read DecodeResult struct from WASM memory (wasm32, 12 bytes)
-	resultBytes, ok := mem.Read(resultPtr, 12)
+	count, ok := mem.ReadUint32Le(countPtr)
 	if !ok {
-		return nil, fmt.Errorf("failed to read result struct from WASM memory")
+		return nil, fmt.Errorf("failed to read result count from WASM memory")
 	}
+	defer in.guestFreeResults(hctx, arrayPtr, count)
 
-	textPtr := binary.LittleEndian.Uint32(resultBytes[0:4])
-	formatVal := int32(binary.LittleEndian.Uint32(resultBytes[4:8]))
+	// Read the DecodeResult* array (wasm32, 4 bytes per pointer)
+	ptrs, ok := mem.Read(arrayPtr, count*4)
+	if !ok {
+		return nil, fmt.Errorf("failed to read result array from WASM memory")
+	}
 
-	// Read text string (null-terminated, bounded read)
-	text := ""
-	if textPtr != 0 {
-		textBytes, ok := mem.Read(textPtr, 4096)
-		if ok {
-			text = cString(textBytes)
+	results := make([]*DecodeResult, 0, count)
+	for i := uint32(0); i < count; i++ {
+		resultPtr := binary.LittleEndian.Uint32(ptrs[i*4 : i*4+4])
+		if resultPtr == 0 {
+			continue
+		}
+		result, err := readDecodeResult(mem, resultPtr)
+		if err != nil {
+			return nil, err
 		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// prepareDecode copies pixel data into guest memory and builds a configured DecodeOptions
+// struct. The returned release function frees both allocations.
+func (in *instance) prepareDecode(ctx context.Context, mem api.Memory, data []byte, width, height, channels int, opts *DecodeOptions) (pixelPtr, optsPtr uint32, release func(), err error) {
+	// Allocate guest memory for pixel data
+	pixelSize := width * height * channels
+	pixelPtr, err = in.guestMalloc(ctx, uint64(pixelSize))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	// Write pixel data into guest memory
+	if !mem.Write(pixelPtr, data[:pixelSize]) {
+		in.guestFree(ctx, pixelPtr)
+		return 0, 0, nil, fmt.Errorf("failed to write pixel data to WASM memory")
+	}
+
+	// Allocate and configure decode options
+	optsPtr, err = in.guestCreateOptions(ctx)
+	if err != nil {
+		in.guestFree(ctx, pixelPtr)
+		return 0, 0, nil, err
+	}
+
+	if err := in.guestConfigureOptions(ctx, optsPtr, opts); err != nil {
+		in.guestFreeOptions(ctx, optsPtr)
+		in.guestFree(ctx, pixelPtr)
+		return 0, 0, nil, err
+	}
+
+	release = func() {
+		in.guestFreeOptions(ctx, optsPtr)
+		in.guestFree(ctx, pixelPtr)
+	}
+	return pixelPtr, optsPtr, release, nil
+}
+
+// decodeResultSize is sizeof(DecodeResult) on wasm32.
+const decodeResultSize = 124
+
+// readDecodeResult reads a DecodeResult struct from guest memory.
+func readDecodeResult(mem api.Memory, resultPtr uint32) (*DecodeResult, error) {
+	// Read DecodeResult struct from WASM memory (wasm32 layout):
+	// text(0) format(4) confidence(8) x1..y4(12..44)
+	// symbology_identifier(44) ec_level(48) version(52) orientation(56)
+	// is_mirrored(60) is_inverted(64) line_count(68) content_type(72) has_eci(76)
+	// text_length(80) bytes(84) bytes_length(88) bytes_eci(92) bytes_eci_length(96)
+	// sequence_size(100) sequence_index(104) sequence_id(108)
+	// error_type(112) error_message(116) ean_add_on(120)
+	resultBytes, ok := mem.Read(resultPtr, decodeResultSize)
+	if !ok {
+		return nil, fmt.Errorf("failed to read result struct from WASM memory")
+	}
+	u32 := func(off int) uint32 { return binary.LittleEndian.Uint32(resultBytes[off : off+4]) }
+	i32 := func(off int) int { return int(int32(u32(off))) }
+
+	// Corner points: top-left, top-right, bottom-right, bottom-left
+	points := make([]image.Point, 4)
+	for i := range points {
+		off := 12 + i*8
+		points[i] = image.Point{X: i32(off), Y: i32(off + 4)}
+	}
+
+	text, err := readGuestBytes(mem, u32(0), u32(80))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result text: %w", err)
+	}
+	bytes, err := readGuestBytes(mem, u32(84), u32(88))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result bytes: %w", err)
+	}
+	bytesECI, err := readGuestBytes(mem, u32(92), u32(96))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result ECI bytes: %w", err)
 	}
 
 	return &DecodeResult{
 		Success: true,
-		Text:    text,
-		Format:  formatToString(int(formatVal)),
+		Text:    string(text),
+		Format:  formatToString(i32(4)),
+		Points:  points,
+
+		SymbologyIdentifier: readGuestString(mem, u32(44)),
+		ECLevel:             readGuestString(mem, u32(48)),
+		Version:             readGuestString(mem, u32(52)),
+		Orientation:         i32(56),
+		IsMirrored:          u32(60) != 0,
+		IsInverted:          u32(64) != 0,
+		LineCount:           i32(68),
+		ContentType:         i32(72),
+		HasECI:              u32(76) != 0,
+		Bytes:               bytes,
+		BytesECI:            bytesECI,
+		SequenceSize:        i32(100),
+		SequenceIndex:       i32(104),
+		SequenceID:          readGuestString(mem, u32(108)),
+		ErrorCode:           i32(112),
+		ErrorMessage:        readGuestString(mem, u32(116)),
+		AddOn:               readGuestString(mem, u32(120)),
 	}, nil
 }
 
+// readGuestBytes copies length bytes at ptr out of guest memory.
+func readGuestBytes(mem api.Memory, ptr, length uint32) ([]byte, error) {
+	if ptr == 0 || length == 0 {
+		return []byte{}, nil
+	}
+	b, ok := mem.Read(ptr, length)
+	if !ok {
+		return nil, fmt.Errorf("out of range read: ptr=%d, length=%d", ptr, length)
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// readGuestString reads a null-terminated string from guest memory.
+// The read is bounded to 4096 bytes and to the end of linear memory.
+func readGuestString(mem api.Memory, ptr uint32) string {
+	if ptr == 0 || ptr >= mem.Size() {
+		return ""
+	}
+	n := mem.Size() - ptr
+	if n > 4096 {
+		n = 4096
+	}
+	b, ok := mem.Read(ptr, n)
+	if !ok {
+		return ""
+	}
+	return cString(b)
+}
+
 // validateDecodeInput checks pixel data, dimensions, and channels before entering WASM.
 func validateDecodeInput(data []byte, width, height, channels int) error {
 	if len(data) == 0 {
@@ -225,10 +686,10 @@ func validateDecodeInput(data []byte, width, height, channels int) error {
 }
 
 // guestMalloc allocates memory in the WASM guest via the zxing_malloc export.
-func (r *Runtime) guestMalloc(ctx context.Context, size uint64) (uint32, error) {
-	fn := r.module.ExportedFunction("zxing_malloc")
+func (in *instance) guestMalloc(ctx context.Context, size uint64) (uint32, error) {
+	fn := in.module.ExportedFunction("zxing_malloc")
 	if fn == nil {
-		return 0, fmt.Errorf("zxing_malloc not exported in WASM module")
+		return 0, notExported("zxing_malloc")
 	}
 	res, err := fn.Call(ctx, size)
 	if err != nil {
@@ -242,17 +703,17 @@ func (r *Runtime) guestMalloc(ctx context.Context, size uint64) (uint32, error)
 }
 
 // guestFree releases guest memory allocated by guestMalloc.
-func (r *Runtime) guestFree(ctx context.Context, ptr uint32) {
-	if fn := r.module.ExportedFunction("zxing_free"); fn != nil {
+func (in *instance) guestFree(ctx context.Context, ptr uint32) {
+	if fn := in.module.ExportedFunction("zxing_free"); fn != nil {
 		fn.Call(ctx, uint64(ptr))
 	}
 }
 
 // guestCreateOptions allocates a default DecodeOptions struct in guest memory.
-func (r *Runtime) guestCreateOptions(ctx context.Context) (uint32, error) {
-	fn := r.module.ExportedFunction("create_default_options")
+func (in *instance) guestCreateOptions(ctx context.Context) (uint32, error) {
+	fn := in.module.ExportedFunction("create_default_options")
 	if fn == nil {
-		return 0, fmt.Errorf("create_default_options not exported in WASM module")
+		return 0, notExported("create_default_options")
 	}
 	res, err := fn.Call(ctx)
 	if err != nil {
@@ -266,19 +727,19 @@ func (r *Runtime) guestCreateOptions(ctx context.Context) (uint32, error) {
 }
 
 // guestFreeOptions releases a DecodeOptions struct in guest memory.
-func (r *Runtime) guestFreeOptions(ctx context.Context, ptr uint32) {
-	if fn := r.module.ExportedFunction("free_options"); fn != nil {
+func (in *instance) guestFreeOptions(ctx context.Context, ptr uint32) {
+	if fn := in.module.ExportedFunction("free_options"); fn != nil {
 		fn.Call(ctx, uint64(ptr))
 	}
 }
 
 // guestConfigureOptions writes all DecodeOptions fields via the configure_decode_options export.
-func (r *Runtime) guestConfigureOptions(ctx context.Context, optsPtr uint32, opts *DecodeOptions) error {
-	fn := r.module.ExportedFunction("configure_decode_options")
+func (in *instance) guestConfigureOptions(ctx context.Context, optsPtr uint32, opts *DecodeOptions) error {
+	fn := in.module.ExportedFunction("configure_decode_options")
 	if fn == nil {
-		return fmt.Errorf("configure_decode_options not exported in WASM module")
+		return notExported("configure_decode_options")
 	}
-	formats := 0xFFFF // FORMAT_ALL
+	formats := 0xFFFFF // FORMAT_ALL
 	tryHarder := 1
 	tryRotate := 1
 	tryInvert := 0
@@ -312,31 +773,172 @@ func (r *Runtime) guestConfigureOptions(ctx context.Context, optsPtr uint32, opt
 	if err != nil {
 		return fmt.Errorf("failed to configure decode options: %w", err)
 	}
+
+	if opts == nil {
+		return nil
+	}
+	if opts.hasTuning() {
+		if err := in.guestConfigureTuning(ctx, optsPtr, opts); err != nil {
+			return err
+		}
+	}
+	if opts.TextMode != 0 {
+		if err := in.guestConfigureTextMode(ctx, optsPtr, opts.TextMode); err != nil {
+			return err
+		}
+	}
+	if opts.CharacterSet != "" {
+		if err := in.guestConfigureCharset(ctx, optsPtr, opts.CharacterSet); err != nil {
+			return err
+		}
+	}
+	if opts.EANAddOn != 0 {
+		if err := in.guestConfigureEANAddOn(ctx, optsPtr, opts.EANAddOn); err != nil {
+			return err
+		}
+	}
+	if opts.Symbology != nil {
+		return in.guestConfigureSymbology(ctx, optsPtr, opts.Symbology)
+	}
 	return nil
 }
 
-// guestFreeResult releases a DecodeResult struct in guest memory.
-func (r *Runtime) guestFreeResult(ctx context.Context, ptr uint32) {
-	if fn := r.module.ExportedFunction("free_result"); fn != nil {
-		fn.Call(ctx, uint64(ptr))
+// guestConfigureSymbology writes the symbology options via the configure_decode_symbology export.
+func (in *instance) guestConfigureSymbology(ctx context.Context, optsPtr uint32, opts *SymbologyOptions) error {
+	fn := in.module.ExportedFunction("configure_decode_symbology")
+	if fn == nil {
+		return notExported("configure_decode_symbology")
+	}
+	flag := func(b bool) uint64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	res, err := fn.Call(ctx, uint64(optsPtr), flag(opts.Code39Extended), flag(opts.Code39CheckDigit),
+		flag(opts.ITFCheckDigit), uint64(opts.ITFMinLength), uint64(opts.ITFMaxLength), flag(opts.CodabarStartStop), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure symbology options: %w", err)
+	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure symbology options: %w", in.guestError(in.module.Memory()))
+	}
+	return nil
+}
+
+// guestConfigureEANAddOn selects the add-on handling via the configure_decode_ean_add_on export.
+func (in *instance) guestConfigureEANAddOn(ctx context.Context, optsPtr uint32, mode int) error {
+	fn := in.module.ExportedFunction("configure_decode_ean_add_on")
+	if fn == nil {
+		return notExported("configure_decode_ean_add_on")
+	}
+	res, err := fn.Call(ctx, uint64(optsPtr), uint64(mode), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure EAN add-on: %w", err)
 	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure EAN add-on: %w", in.guestError(in.module.Memory()))
+	}
+	return nil
 }
 
-// guestLastError reads the last error string from the WASM module.
-func (r *Runtime) guestLastError(ctx context.Context, mem api.Memory) string {
-	fn := r.module.ExportedFunction("get_last_error")
+// guestConfigureCharset sets the fallback character set via the configure_decode_charset export.
+func (in *instance) guestConfigureCharset(ctx context.Context, optsPtr uint32, charset string) error {
+	fn := in.module.ExportedFunction("configure_decode_charset")
 	if fn == nil {
-		return "unknown error (get_last_error not exported)"
+		return notExported("configure_decode_charset")
 	}
-	res, err := fn.Call(ctx)
-	if err != nil || res == nil || res[0] == 0 {
-		return "unknown error"
+	mem := in.module.Memory()
+	namePtr, err := in.guestCString(ctx, mem, charset)
+	if err != nil {
+		return err
 	}
-	errBytes, ok := mem.Read(uint32(res[0]), 256)
+	defer in.guestFree(ctx, namePtr)
+
+	res, err := fn.Call(ctx, uint64(optsPtr), uint64(namePtr), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure character set: %w", err)
+	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure character set: %w", in.guestError(mem))
+	}
+	return nil
+}
+
+// guestConfigureTextMode selects the result text rendering via the configure_decode_text_mode export.
+func (in *instance) guestConfigureTextMode(ctx context.Context, optsPtr uint32, mode int) error {
+	fn := in.module.ExportedFunction("configure_decode_text_mode")
+	if fn == nil {
+		return notExported("configure_decode_text_mode")
+	}
+	res, err := fn.Call(ctx, uint64(optsPtr), uint64(mode), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure text mode: %w", err)
+	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure text mode: %w", in.guestError(in.module.Memory()))
+	}
+	return nil
+}
+
+// guestConfigureTuning writes the reader tuning fields via the configure_decode_tuning export.
+func (in *instance) guestConfigureTuning(ctx context.Context, optsPtr uint32, opts *DecodeOptions) error {
+	fn := in.module.ExportedFunction("configure_decode_tuning")
+	if fn == nil {
+		return notExported("configure_decode_tuning")
+	}
+	isPure, returnErrors := 0, 0
+	if opts.IsPure {
+		isPure = 1
+	}
+	if opts.ReturnErrors {
+		returnErrors = 1
+	}
+	res, err := fn.Call(ctx, uint64(optsPtr), uint64(opts.Binarizer), uint64(opts.DownscaleThreshold),
+		uint64(opts.DownscaleFactor), uint64(isPure), uint64(opts.MinLineCount), uint64(opts.MaxSymbols),
+		uint64(returnErrors), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure decode tuning: %w", err)
+	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure decode tuning: %w", in.guestError(in.module.Memory()))
+	}
+	return nil
+}
+
+// guestFreeResult releases a DecodeResult struct in guest memory.
+func (in *instance) guestFreeResult(ctx context.Context, ptr uint32) {
+	if fn := in.module.ExportedFunction("free_result"); fn != nil {
+		fn.Call(ctx, uint64(ptr))
+	}
+}
+
+// guestFreeResults releases a DecodeResult* array returned by decode_barcodes_pixels.
+func (in *instance) guestFreeResults(ctx context.Context, ptr, count uint32) {
+	if fn := in.module.ExportedFunction("free_results"); fn != nil {
+		fn.Call(ctx, uint64(ptr), uint64(count))
+	}
+}
+
+// zxingErrorSize is sizeof(ZXingError) on wasm32: int code, char message[256].
+const zxingErrorSize = 4 + 256
+
+// guestError returns the error the last wrapper call of the instance reported
+// through its ZXingError out-parameter.
+func (in *instance) guestError(mem api.Memory) *GuestError {
+	b, ok := mem.Read(in.errPtr, zxingErrorSize)
 	if !ok {
-		return "failed to read error message"
+		return &GuestError{Message: "failed to read error from WASM memory"}
 	}
-	return cString(errBytes)
+	return &GuestError{
+		Code:    int(int32(binary.LittleEndian.Uint32(b[0:4]))),
+		Message: cString(b[4:]),
+	}
+}
+
+// notExported returns the error for a function missing from the WASM module.
+func notExported(name string) error {
+	return fmt.Errorf("%s not exported in WASM module (rebuild it with 'go run ./cmd/build build-wasm'): %w", name, ErrUnsupported)
 }
 
 // cString reads a null-terminated C string from a byte slice.
@@ -382,24 +984,230 @@ func formatToString(format int) string {
 		return "UPC_A"
 	case 8192:
 		return "UPC_E"
-	case 0xFFFF:
+	case 16384:
+		return "DATABAR"
+	case 32768:
+		return "DATABAR_EXPANDED"
+	case 65536:
+		return "DATABAR_LIMITED"
+	case 131072:
+		return "MICRO_QR_CODE"
+	case 262144:
+		return "RMQR_CODE"
+	case 524288:
+		return "DX_FILM_EDGE"
+	case 0xFFFFF:
 		return "ALL"
 	default:
 		return fmt.Sprintf("Unknown(%d)", format)
 	}
 }
 
-// EncodeText encodes text to a barcode image using the WASM module.
-// WASM encoding is not implemented yet; this always returns an error.
-func (r *Runtime) EncodeText(text string, width, height int) (*EncodeResult, error) {
-	return nil, fmt.Errorf("WASM encode is not implemented")
+// EncodeText encodes text to a module matrix using the WASM module's encode_barcode export.
+// Scaling and quiet zones are left to the caller.
+func (r *Runtime) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (*EncodeResult, error) {
+	if len(text) == 0 {
+		return nil, fmt.Errorf("empty text")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	in, err := r.checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer in.release()
+	mem := in.module.Memory()
+
+	// Only the encode call observes cancellation, as in DecodeImage
+	hctx := context.WithoutCancel(ctx)
+
+	// Copy the text into guest memory; encode_barcode takes an explicit length
+	textPtr, err := in.guestMalloc(hctx, uint64(len(text)))
+	if err != nil {
+		return nil, err
+	}
+	defer in.guestFree(hctx, textPtr)
+	if !mem.Write(textPtr, []byte(text)) {
+		return nil, fmt.Errorf("failed to write text to WASM memory")
+	}
+
+	optsPtr, err := in.guestCreateEncodeOptions(hctx)
+	if err != nil {
+		return nil, err
+	}
+	defer in.guestFreeEncodeOptions(hctx, optsPtr)
+
+	if err := in.guestConfigureEncodeOptions(hctx, mem, optsPtr, opts); err != nil {
+		return nil, err
+	}
+
+	// Call encode_barcode(text, text_length, options, error)
+	encodeFn := in.module.ExportedFunction("encode_barcode")
+	if encodeFn == nil {
+		return nil, notExported("encode_barcode")
+	}
+
+	resultRes, err := encodeFn.Call(ctx, uint64(textPtr), uint64(len(text)), uint64(optsPtr), uint64(in.errPtr))
+	if err != nil {
+		in.discard()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("WASM encode_barcode call canceled: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("WASM encode_barcode call failed: %w", err)
+	}
+	resultPtr := uint32(resultRes[0])
+
+	if resultPtr == 0 {
+		return nil, fmt.Errorf("WASM encode failed: %w", in.guestError(mem))
+	}
+	defer in.guestFreeEncodeResult(hctx, resultPtr)
+
+	return readEncodeResult(mem, resultPtr)
+}
+
+// encodeResultSize is sizeof(EncodeResult) on wasm32.
+const encodeResultSize = 12
+
+// readEncodeResult reads an EncodeResult struct from guest memory.
+func readEncodeResult(mem api.Memory, resultPtr uint32) (*EncodeResult, error) {
+	// Read EncodeResult struct from WASM memory (wasm32 layout):
+	// modules(0) width(4) height(8)
+	resultBytes, ok := mem.Read(resultPtr, encodeResultSize)
+	if !ok {
+		return nil, fmt.Errorf("failed to read encode result struct from WASM memory")
+	}
+	modulesPtr := binary.LittleEndian.Uint32(resultBytes[0:4])
+	width := int(int32(binary.LittleEndian.Uint32(resultBytes[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(resultBytes[8:12])))
+	if width <= 0 || height <= 0 || width > (1<<30)/height {
+		return nil, fmt.Errorf("invalid encode result size: %dx%d", width, height)
+	}
+
+	modules, err := readGuestBytes(mem, modulesPtr, uint32(width*height))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encoded modules: %w", err)
+	}
+	if len(modules) != width*height {
+		return nil, fmt.Errorf("encode result has no module data")
+	}
+
+	return &EncodeResult{
+		Success: true,
+		Width:   width,
+		Height:  height,
+		Data:    modules,
+	}, nil
 }
 
-// Close releases all WASM runtime resources. It is idempotent.
+// guestCreateEncodeOptions allocates a default EncodeOptions struct in guest memory.
+func (in *instance) guestCreateEncodeOptions(ctx context.Context) (uint32, error) {
+	fn := in.module.ExportedFunction("create_encode_options")
+	if fn == nil {
+		return 0, notExported("create_encode_options")
+	}
+	res, err := fn.Call(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create encode options: %w", err)
+	}
+	ptr := uint32(res[0])
+	if ptr == 0 {
+		return 0, fmt.Errorf("WASM create_encode_options returned null")
+	}
+	return ptr, nil
+}
+
+// guestFreeEncodeOptions releases an EncodeOptions struct in guest memory.
+func (in *instance) guestFreeEncodeOptions(ctx context.Context, ptr uint32) {
+	if fn := in.module.ExportedFunction("free_encode_options"); fn != nil {
+		fn.Call(ctx, uint64(ptr))
+	}
+}
+
+// guestConfigureEncodeOptions writes all EncodeOptions fields via the configure_encode_options export.
+func (in *instance) guestConfigureEncodeOptions(ctx context.Context, mem api.Memory, optsPtr uint32, opts *EncodeOptions) error {
+	fn := in.module.ExportedFunction("configure_encode_options")
+	if fn == nil {
+		return notExported("configure_encode_options")
+	}
+	format := 1 // FORMAT_QR_CODE
+	var ecLevel string
+	if opts != nil {
+		if opts.Format != 0 {
+			format = opts.Format
+		}
+		ecLevel = opts.ECLevel
+	}
+
+	// The EC level is passed as a NUL-terminated string, or null when unset
+	ecPtr, err := in.guestCString(ctx, mem, ecLevel)
+	if err != nil {
+		return err
+	}
+	defer in.guestFree(ctx, ecPtr)
+
+	res, err := fn.Call(ctx, uint64(optsPtr), uint64(format), uint64(ecPtr), uint64(in.errPtr))
+	if err != nil {
+		return fmt.Errorf("failed to configure encode options: %w", err)
+	}
+	if int32(res[0]) != 0 {
+		return fmt.Errorf("failed to configure encode options: %w", in.guestError(mem))
+	}
+	return nil
+}
+
+// guestCString copies s into guest memory as a NUL-terminated string. An empty
+// s yields a null pointer; the caller frees the result with guestFree.
+func (in *instance) guestCString(ctx context.Context, mem api.Memory, s string) (uint32, error) {
+	if s == "" {
+		return 0, nil
+	}
+	ptr, err := in.guestMalloc(ctx, uint64(len(s)+1))
+	if err != nil {
+		return 0, err
+	}
+	if !mem.Write(ptr, append([]byte(s), 0)) {
+		in.guestFree(ctx, ptr)
+		return 0, fmt.Errorf("failed to write string to WASM memory")
+	}
+	return ptr, nil
+}
+
+// guestFreeEncodeResult releases an EncodeResult struct in guest memory.
+func (in *instance) guestFreeEncodeResult(ctx context.Context, ptr uint32) {
+	if fn := in.module.ExportedFunction("free_encode_result"); fn != nil {
+		fn.Call(ctx, uint64(ptr))
+	}
+}
+
+// Close releases all WASM runtime resources. It waits for calls in progress to
+// return their instances; callers still waiting for one get ErrClosed.
+// It is idempotent.
 func (r *Runtime) Close() error {
+	r.mu.Lock()
+	if r.closing || r.pool == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closing = true
+	pool := r.pool
+	r.mu.Unlock()
+
+	for i := 0; i < cap(pool); i++ {
+		if in := <-pool; in.module != nil {
+			in.module.Close(context.Background())
+		}
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	close(pool)
+	r.pool = nil
+	r.closing = false
+
 	var firstErr error
 	if r.compiled != nil {
 		if err := r.compiled.Close(context.Background()); err != nil && firstErr == nil {
@@ -413,6 +1221,5 @@ func (r *Runtime) Close() error {
 		}
 		r.runtime = nil
 	}
-	r.module = nil
 	return firstErr
 }
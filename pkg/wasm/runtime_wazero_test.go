@@ -1,16 +1,22 @@
-//go:build !cgo || !(linux || windows)
+//go:build (!cgo || !(linux || windows)) && !(js && wasm)
 
 package wasm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/png"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/tetratelabs/wazero"
 )
 
 func TestWazeroLoadAndDecode(t *testing.T) {
@@ -33,8 +39,8 @@ func TestWazeroRequiredExports(t *testing.T) {
 	}
 	defer rt.Close()
 
-	for _, name := range []string{"zxing_malloc", "zxing_free", "configure_decode_options", "decode_barcode_pixels", "create_default_options", "free_options", "free_result", "get_last_error"} {
-		if rt.module.ExportedFunction(name) == nil {
+	for _, name := range []string{"zxing_malloc", "zxing_free", "configure_decode_options", "configure_decode_tuning", "decode_barcode_pixels", "decode_barcodes_pixels", "create_default_options", "free_options", "free_result", "free_results", "get_last_error", "zxing_abi_version", "create_encode_options", "configure_encode_options", "free_encode_options", "encode_barcode", "free_encode_result"} {
+		if _, ok := rt.compiled.ExportedFunctions()[name]; !ok {
 			t.Fatalf("required export %q is missing", name)
 		}
 	}
@@ -91,6 +97,65 @@ func TestWazeroDecodeQRCode(t *testing.T) {
 	t.Logf("Decoded text: %s, format: %s", result.Text, result.Format)
 }
 
+func TestWazeroDecodeMultiQRCode(t *testing.T) {
+	rt := NewRuntime()
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		t.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+	defer rt.Close()
+
+	file, err := os.Open("../../data/qrcode_www.bing.com.png")
+	if err != nil {
+		t.Fatalf("Failed to open test image: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode test image: %v", err)
+	}
+
+	// Place two copies of the QR code side by side
+	bounds := img.Bounds()
+	width := bounds.Dx() * 2
+	height := bounds.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			rgba.Set(x, y, c)
+			rgba.Set(bounds.Dx()+x, y, c)
+		}
+	}
+
+	results, err := rt.DecodeMultiImage(context.Background(), rgba.Pix, width, height, 4, nil)
+	if err != nil {
+		t.Fatalf("Failed to decode QR codes: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Format != "QR_CODE" {
+			t.Errorf("unexpected decoded format: %q", result.Format)
+		}
+		if result.SymbologyIdentifier != "]Q1" {
+			t.Errorf("unexpected symbology identifier: %q", result.SymbologyIdentifier)
+		}
+		if string(result.Bytes) != result.Text {
+			t.Errorf("raw bytes %q do not match text %q", result.Bytes, result.Text)
+		}
+		if len(result.Points) != 4 {
+			t.Fatalf("expected 4 points, got %d", len(result.Points))
+		}
+		for i, p := range result.Points {
+			if !p.In(rgba.Bounds()) {
+				t.Errorf("point %d %v is outside the image %v", i, p, rgba.Bounds())
+			}
+		}
+	}
+}
+
 func TestDecodeImageRejectsInvalidInput(t *testing.T) {
 	rt := NewRuntime()
 	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
@@ -197,6 +262,78 @@ func TestRuntimeCloseIsConcurrentAndIdempotent(t *testing.T) {
 	}
 }
 
+func TestRuntimePoolCheckout(t *testing.T) {
+	rt := NewRuntimeWithConcurrency(2)
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		t.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+	defer rt.Close()
+
+	// Two calls hold separate instances at the same time.
+	first, err := rt.checkout(context.Background())
+	if err != nil {
+		t.Fatalf("first checkout failed: %v", err)
+	}
+	second, err := rt.checkout(context.Background())
+	if err != nil {
+		t.Fatalf("second checkout failed: %v", err)
+	}
+	if first.module == second.module {
+		t.Fatal("expected distinct module instances")
+	}
+
+	// A third caller waits for a free instance and gives up with its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rt.checkout(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded while the pool is exhausted, got %v", err)
+	}
+
+	// Releasing an instance hands it to the next waiter.
+	got := make(chan *instance)
+	go func() {
+		in, err := rt.checkout(context.Background())
+		if err != nil {
+			t.Errorf("waiting checkout failed: %v", err)
+		}
+		got <- in
+	}()
+	first.release()
+	if in := <-got; in != first {
+		t.Error("expected the released instance to be reused")
+	} else {
+		in.release()
+	}
+	second.release()
+}
+
+func TestRuntimeCloseWaitsForCalls(t *testing.T) {
+	rt := NewRuntimeWithConcurrency(1)
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		t.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+
+	in, err := rt.checkout(context.Background())
+	if err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	closed := make(chan error)
+	go func() { closed <- rt.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a call still held an instance")
+	case <-time.After(20 * time.Millisecond):
+	}
+	in.release()
+	if err := <-closed; err != nil {
+		t.Fatalf("close returned error: %v", err)
+	}
+	if _, err := rt.DecodeImage(context.Background(), make([]byte, 4), 2, 2, 1, nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+}
+
 func TestDecodeImageHonorsCanceledContext(t *testing.T) {
 	rt := NewRuntime()
 	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
@@ -213,6 +350,146 @@ func TestDecodeImageHonorsCanceledContext(t *testing.T) {
 	}
 }
 
+func TestCanceledCallKeepsRuntimeUsable(t *testing.T) {
+	rt := NewRuntime()
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		t.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+	defer rt.Close()
+	compiled := rt.compiled
+
+	// A large noisy frame with TryHarder takes far longer than the deadline.
+	const width, height = 3000, 2000
+	noise := make([]byte, width*height)
+	for i := range noise {
+		noise[i] = byte(i*7919 ^ i>>5)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err := rt.DecodeImage(ctx, noise, width, height, 1, &DecodeOptions{TryHarder: true, TryRotate: true, TryInvert: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Skipf("decode was not interrupted by the deadline: %v", err)
+	}
+
+	if !rt.IsReady() {
+		t.Fatal("runtime should stay ready after a canceled call")
+	}
+
+	// The next call runs on a fresh instance of the same compiled module and
+	// reaches the guest decoder, which reports that the blank image has no barcode.
+	_, err = rt.DecodeImage(context.Background(), make([]byte, 64), 8, 8, 1, nil)
+	var guestErr *GuestError
+	if !errors.As(err, &guestErr) {
+		t.Fatalf("expected a guest decode error after cancellation, got %v", err)
+	}
+	if rt.compiled != compiled {
+		t.Error("module should not be recompiled after a canceled call")
+	}
+}
+
+func TestResetCompilationCacheKeepsOtherVersions(t *testing.T) {
+	dir := t.TempDir()
+	// wazero creates its versioned subdirectory when the cache is opened
+	if _, err := wazero.NewCompilationCacheWithDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	current := filepath.Join(dir, wazeroCacheSubdir())
+	if _, err := os.Stat(current); err != nil {
+		t.Fatalf("wazero did not create %s: %v", current, err)
+	}
+	other := filepath.Join(dir, "wazero-v0.0.1-"+runtime.GOARCH+"-"+runtime.GOOS)
+	if err := os.Mkdir(other, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := resetCompilationCache(dir); err != nil {
+		t.Fatalf("resetCompilationCache() failed: %v", err)
+	}
+	if _, err := os.Stat(current); !os.IsNotExist(err) {
+		t.Errorf("cache of the running wazero version was kept: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("cache of another wazero version was removed: %v", err)
+	}
+}
+
+func TestCompilationCache(t *testing.T) {
+	dir := t.TempDir()
+	initialize := func() {
+		t.Helper()
+		rt := NewRuntime()
+		rt.SetCompilationCacheDir(dir)
+		if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+			t.Fatalf("Failed to initialize wazero runtime: %v", err)
+		}
+		if !rt.IsReady() {
+			t.Fatal("Runtime should be ready after Initialize")
+		}
+		rt.Close()
+	}
+	cachedFiles := func() []string {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(dir, "wazero-*", "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	initialize()
+	files := cachedFiles()
+	if len(files) == 0 {
+		t.Fatal("compiled module was not written to the cache directory")
+	}
+
+	// Runtimes of one process share the cache
+	first, err := sharedCompilationCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := sharedCompilationCache(dir); second != first {
+		t.Error("expected runtimes using the same directory to share one cache")
+	}
+
+	// A new process loads the module from disk; a corrupted entry is
+	// recompiled and the cache rebuilt.
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte("corrupted"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	compilationCaches.Lock()
+	delete(compilationCaches.byDir, dir)
+	compilationCaches.Unlock()
+
+	initialize()
+	for _, file := range cachedFiles() {
+		if data, err := os.ReadFile(file); err == nil && string(data) == "corrupted" {
+			t.Errorf("corrupted cache entry %s was kept", file)
+		}
+	}
+	initialize()
+	if len(cachedFiles()) == 0 {
+		t.Error("cache was not rebuilt after the corrupted entry was dropped")
+	}
+
+	// An unusable cache directory is reported instead of silently skipped
+	blocker := filepath.Join(dir, "not-a-directory")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rt := NewRuntime()
+	rt.SetCompilationCacheDir(blocker)
+	err = rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm")
+	if err == nil || !strings.Contains(err.Error(), "compilation cache") {
+		t.Fatalf("expected compilation cache error, got %v", err)
+	}
+	if rt.IsReady() {
+		t.Error("runtime should not be ready after a cache error")
+	}
+	rt.Close()
+}
+
 func TestInitializeFailureLeavesRuntimeNotReady(t *testing.T) {
 	rt := NewRuntime()
 	err := rt.Initialize(context.Background(), "/nonexistent/path/to/wasm.wasm")
@@ -328,18 +605,79 @@ func TestDecodeWithIncompatibleFormat(t *testing.T) {
 	t.Logf("Expected decode failure with incompatible format: %s", result.ErrorMessage)
 }
 
-func TestEncodeTextReturnsError(t *testing.T) {
+func TestWazeroEncodeRoundTrip(t *testing.T) {
 	rt := NewRuntime()
 	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
 		t.Fatalf("Failed to initialize wazero runtime: %v", err)
 	}
 	defer rt.Close()
 
-	_, err := rt.EncodeText("test", 256, 256)
-	if err == nil {
-		t.Fatal("expected error from unimplemented EncodeText")
+	ctx := context.Background()
+	result, err := rt.EncodeText(ctx, "https://www.bing.com", &EncodeOptions{Format: 1, ECLevel: "H"})
+	if err != nil {
+		t.Fatalf("EncodeText failed: %v", err)
+	}
+	if result.Width <= 0 || result.Height <= 0 || len(result.Data) != result.Width*result.Height {
+		t.Fatalf("unexpected module matrix: %dx%d, %d bytes", result.Width, result.Height, len(result.Data))
+	}
+
+	// Render at 4 pixels per module with a 4 module quiet zone and decode it back
+	const scale, quiet = 4, 4
+	width := (result.Width + 2*quiet) * scale
+	height := (result.Height + 2*quiet) * scale
+	pixels := make([]byte, width*height)
+	for i := range pixels {
+		pixels[i] = 0xFF
+	}
+	for y := 0; y < result.Height; y++ {
+		for x := 0; x < result.Width; x++ {
+			if result.Data[y*result.Width+x] == 0 {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					pixels[((y+quiet)*scale+py)*width+(x+quiet)*scale+px] = 0
+				}
+			}
+		}
+	}
+
+	decoded, err := rt.DecodeImage(ctx, pixels, width, height, 1, &DecodeOptions{Formats: 1, TryHarder: true})
+	if err != nil {
+		t.Fatalf("DecodeImage failed: %v", err)
+	}
+	if decoded.Text != "https://www.bing.com" {
+		t.Fatalf("round trip text = %q", decoded.Text)
+	}
+	if decoded.ECLevel != "H" {
+		t.Errorf("round trip EC level = %q, want H", decoded.ECLevel)
+	}
+}
+
+func TestWazeroEncodeInvalidInput(t *testing.T) {
+	rt := NewRuntime()
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		t.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+	defer rt.Close()
+
+	ctx := context.Background()
+	if _, err := rt.EncodeText(ctx, "", nil); err == nil {
+		t.Error("expected error for empty text")
+	}
+	if _, err := rt.EncodeText(ctx, "test", &EncodeOptions{Format: 0xFFFFF}); err == nil {
+		t.Error("expected error for multi-format encode")
+	}
+	_, err := rt.EncodeText(ctx, "not digits", &EncodeOptions{Format: 256})
+	var guestErr *GuestError
+	if !errors.As(err, &guestErr) || guestErr.Code != 5 { // ZXING_ERROR_INVALID_INPUT
+		t.Errorf("expected GuestError with ZXING_ERROR_INVALID_INPUT for invalid EAN-13 content, got %v", err)
+	}
+
+	// The runtime must stay usable after a failed encode
+	if _, err := rt.EncodeText(ctx, "test", nil); err != nil {
+		t.Fatalf("EncodeText after failure: %v", err)
 	}
-	t.Logf("EncodeText correctly returned error: %v", err)
 }
 
 func TestCloseReturnsNoErrorWhenAlreadyClosed(t *testing.T) {
@@ -365,7 +703,10 @@ func TestFormatToString(t *testing.T) {
 		{8, "CODE_39"}, {16, "CODE_93"}, {32, "CODE_128"},
 		{64, "DATA_MATRIX"}, {128, "EAN_8"}, {256, "EAN_13"},
 		{512, "ITF"}, {1024, "MAXICODE"}, {2048, "PDF_417"},
-		{4096, "UPC_A"}, {8192, "UPC_E"}, {0xFFFF, "ALL"},
+		{4096, "UPC_A"}, {8192, "UPC_E"}, {16384, "DATABAR"},
+		{32768, "DATABAR_EXPANDED"}, {65536, "DATABAR_LIMITED"},
+		{131072, "MICRO_QR_CODE"}, {262144, "RMQR_CODE"},
+		{524288, "DX_FILM_EDGE"}, {0xFFFFF, "ALL"},
 	}
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("%d", tt.format), func(t *testing.T) {
@@ -376,3 +717,51 @@ func TestFormatToString(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkRuntimeParallelDecode measures decode throughput with one pool
+// instance per GOMAXPROCS; compare runs with -cpu 1,2,4,8,16.
+func BenchmarkRuntimeParallelDecode(b *testing.B) {
+	file, err := os.Open("../../data/qrcode_www.bing.com.png")
+	if err != nil {
+		b.Fatalf("Failed to open test image: %v", err)
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		b.Fatalf("Failed to decode test image: %v", err)
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+
+	rt := NewRuntimeWithConcurrency(runtime.GOMAXPROCS(0))
+	if err := rt.Initialize(context.Background(), "../../wasm/zxingwrapper.wasm"); err != nil {
+		b.Fatalf("Failed to initialize wazero runtime: %v", err)
+	}
+	defer rt.Close()
+
+	// Warm up every instance so instantiation is not measured
+	var wg sync.WaitGroup
+	for i := 0; i < rt.Concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rt.DecodeImage(context.Background(), gray.Pix, bounds.Dx(), bounds.Dy(), 1, nil)
+		}()
+	}
+	wg.Wait()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := rt.DecodeImage(context.Background(), gray.Pix, bounds.Dx(), bounds.Dy(), 1, nil); err != nil {
+				b.Errorf("decode failed: %v", err)
+				return
+			}
+		}
+	})
+}
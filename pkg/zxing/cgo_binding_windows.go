@@ -20,85 +20,63 @@ import "C"
 
 import (
 	"fmt"
+	"image"
 	"unsafe"
 )
 
-// BarcodeFormat represents the barcode format type.
-type BarcodeFormat int
-
-const (
-	FormatNone       BarcodeFormat = C.FORMAT_NONE
-	FormatQRCode     BarcodeFormat = C.FORMAT_QR_CODE
-	FormatAztec      BarcodeFormat = C.FORMAT_AZTEC
-	FormatCodabar    BarcodeFormat = C.FORMAT_CODABAR
-	FormatCode39     BarcodeFormat = C.FORMAT_CODE_39
-	FormatCode93     BarcodeFormat = C.FORMAT_CODE_93
-	FormatCode128    BarcodeFormat = C.FORMAT_CODE_128
-	FormatDataMatrix BarcodeFormat = C.FORMAT_DATA_MATRIX
-	FormatEAN8       BarcodeFormat = C.FORMAT_EAN_8
-	FormatEAN13      BarcodeFormat = C.FORMAT_EAN_13
-	FormatITF        BarcodeFormat = C.FORMAT_ITF
-	FormatMaxiCode   BarcodeFormat = C.FORMAT_MAXICODE
-	FormatPDF417     BarcodeFormat = C.FORMAT_PDF_417
-	FormatUPCA       BarcodeFormat = C.FORMAT_UPC_A
-	FormatUPCE       BarcodeFormat = C.FORMAT_UPC_E
-	FormatAll        BarcodeFormat = C.FORMAT_ALL
-)
-
-// String returns the string representation of the barcode format.
-func (f BarcodeFormat) String() string {
-	switch f {
-	case FormatNone:
-		return "None"
-	case FormatQRCode:
-		return "QR Code"
-	case FormatAztec:
-		return "Aztec"
-	case FormatCodabar:
-		return "Codabar"
-	case FormatCode39:
-		return "Code 39"
-	case FormatCode93:
-		return "Code 93"
-	case FormatCode128:
-		return "Code 128"
-	case FormatDataMatrix:
-		return "Data Matrix"
-	case FormatEAN8:
-		return "EAN-8"
-	case FormatEAN13:
-		return "EAN-13"
-	case FormatITF:
-		return "ITF"
-	case FormatMaxiCode:
-		return "MaxiCode"
-	case FormatPDF417:
-		return "PDF417"
-	case FormatUPCA:
-		return "UPC-A"
-	case FormatUPCE:
-		return "UPC-E"
-	case FormatAll:
-		return "All"
-	default:
-		return fmt.Sprintf("Unknown(%d)", f)
-	}
-}
-
 // CGODecodeOptions represents CGO decode options.
 type CGODecodeOptions struct {
-	Formats      BarcodeFormat
+	Formats      Format
 	TryHarder    bool
 	TryRotate    bool
 	TryInvert    bool
 	TryDownscale bool
+
+	Binarizer          Binarizer
+	DownscaleThreshold int
+	DownscaleFactor    int
+	IsPure             bool
+	MinLineCount       int
+	MaxSymbols         int
+	ReturnErrors       bool
+	TextMode           TextMode
+	CharacterSet       string
+	EANAddOn           EANAddOn
+
+	Code39Extended   bool
+	Code39CheckDigit bool
+	ITFCheckDigit    bool
+	ITFMinLength     int
+	ITFMaxLength     int
+	CodabarStartStop bool
 }
 
 // CGODecodeResult represents a CGO decode result.
 type CGODecodeResult struct {
 	Text       string
-	Format     BarcodeFormat
+	Format     Format
 	Confidence float32
+	// Points holds the symbol corners: top-left, top-right, bottom-right, bottom-left.
+	Points []image.Point
+
+	SymbologyIdentifier string
+	ECLevel             string
+	Version             string
+	Orientation         int
+	IsMirrored          bool
+	IsInverted          bool
+	LineCount           int
+	ContentType         int
+	HasECI              bool
+	Bytes               []byte
+	BytesECI            []byte
+	SequenceSize        int
+	SequenceIndex       int
+	SequenceID          string
+	// ErrorType is the ZXingErrorCode of a symbol that failed to decode, 0 otherwise.
+	ErrorType    int
+	ErrorMessage string
+	AddOn        string
 }
 
 // NewDefaultOptions creates default decode options via CGO.
@@ -110,16 +88,33 @@ func NewDefaultOptions() *CGODecodeOptions {
 	defer C.free_options(options)
 
 	return &CGODecodeOptions{
-		Formats:      BarcodeFormat(options.formats),
+		Formats:      Format(options.formats),
 		TryHarder:    options.try_harder != 0,
 		TryRotate:    options.try_rotate != 0,
 		TryInvert:    options.try_invert != 0,
 		TryDownscale: options.try_downscale != 0,
+
+		Binarizer:          Binarizer(options.binarizer),
+		DownscaleThreshold: int(options.downscale_threshold),
+		DownscaleFactor:    int(options.downscale_factor),
+		IsPure:             options.is_pure != 0,
+		MinLineCount:       int(options.min_line_count),
+		MaxSymbols:         int(options.max_number_of_symbols),
+		ReturnErrors:       options.return_errors != 0,
+		TextMode:           TextMode(options.text_mode),
+		EANAddOn:           EANAddOn(options.ean_add_on),
+
+		Code39Extended:   options.code39_extended != 0,
+		Code39CheckDigit: options.code39_check_digit != 0,
+		ITFCheckDigit:    options.itf_check_digit != 0,
+		ITFMinLength:     int(options.itf_min_length),
+		ITFMaxLength:     int(options.itf_max_length),
+		CodabarStartStop: options.codabar_start_stop != 0,
 	}
 }
 
-// Decode decodes a single barcode from the given image path.
-func Decode(imagePath string, options *CGODecodeOptions) (*CGODecodeResult, error) {
+// DecodeFile decodes a single barcode from the image file at imagePath.
+func DecodeFile(imagePath string, options *CGODecodeOptions) (*CGODecodeResult, error) {
 	if options == nil {
 		options = NewDefaultOptions()
 		if options == nil {
@@ -130,29 +125,23 @@ func Decode(imagePath string, options *CGODecodeOptions) (*CGODecodeResult, erro
 	cPath := C.CString(imagePath)
 	defer C.free(unsafe.Pointer(cPath))
 
-	cOptions := C.DecodeOptions{
-		formats:       C.int(options.Formats),
-		try_harder:    C.int(boolToInt(options.TryHarder)),
-		try_rotate:    C.int(boolToInt(options.TryRotate)),
-		try_invert:    C.int(boolToInt(options.TryInvert)),
-		try_downscale: C.int(boolToInt(options.TryDownscale)),
+	cOptions, err := newCDecodeOptions(options)
+	if err != nil {
+		return nil, err
 	}
 
-	result := C.decode_barcode(cPath, &cOptions)
+	var cErr C.ZXingError
+	result := C.decode_barcode(cPath, &cOptions, &cErr)
 	if result == nil {
-		return nil, fmt.Errorf("%s", C.GoString(C.get_last_error()))
+		return nil, newNativeError(&cErr)
 	}
 	defer C.free_result(result)
 
-	return &CGODecodeResult{
-		Text:       C.GoString(result.text),
-		Format:     BarcodeFormat(result.format),
-		Confidence: float32(result.confidence),
-	}, nil
+	return newCGODecodeResult(result), nil
 }
 
-// DecodeMulti decodes multiple barcodes from the given image path.
-func DecodeMulti(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
+// DecodeMultiFile decodes multiple barcodes from the image file at imagePath.
+func DecodeMultiFile(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
 	if options == nil {
 		options = NewDefaultOptions()
 		if options == nil {
@@ -163,21 +152,167 @@ func DecodeMulti(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResul
 	cPath := C.CString(imagePath)
 	defer C.free(unsafe.Pointer(cPath))
 
-	cOptions := C.DecodeOptions{
-		formats:       C.int(options.Formats),
-		try_harder:    C.int(boolToInt(options.TryHarder)),
-		try_rotate:    C.int(boolToInt(options.TryRotate)),
-		try_invert:    C.int(boolToInt(options.TryInvert)),
-		try_downscale: C.int(boolToInt(options.TryDownscale)),
+	cOptions, err := newCDecodeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var count C.int
+	var cErr C.ZXingError
+	results := C.decode_barcodes(cPath, &cOptions, &count, &cErr)
+	if results == nil {
+		return nil, newNativeError(&cErr)
+	}
+	defer C.free_results(results, count)
+
+	return newCGODecodeResults(results, count), nil
+}
+
+// DecodePixels decodes a single barcode from tightly packed pixels with
+// 1 (gray), 2 (gray+alpha), 3 (RGB) or 4 (RGBA) channels.
+func DecodePixels(data []byte, width, height, channels int, options *CGODecodeOptions) (*CGODecodeResult, error) {
+	if err := validatePixels(data, width, height, channels); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = NewDefaultOptions()
+		if options == nil {
+			return nil, fmt.Errorf("failed to create default options")
+		}
+	}
+
+	cOptions, err := newCDecodeOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var cErr C.ZXingError
+	result := C.decode_barcode_pixels((*C.uchar)(unsafe.Pointer(&data[0])),
+		C.int(width), C.int(height), C.int(channels), &cOptions, &cErr)
+	if result == nil {
+		return nil, newNativeError(&cErr)
+	}
+	defer C.free_result(result)
+
+	return newCGODecodeResult(result), nil
+}
+
+// DecodeMultiPixels decodes multiple barcodes from tightly packed pixels.
+// channels has the same meaning as in DecodePixels.
+func DecodeMultiPixels(data []byte, width, height, channels int, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
+	if err := validatePixels(data, width, height, channels); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = NewDefaultOptions()
+		if options == nil {
+			return nil, fmt.Errorf("failed to create default options")
+		}
+	}
+
+	cOptions, err := newCDecodeOptions(options)
+	if err != nil {
+		return nil, err
 	}
 
 	var count C.int
-	results := C.decode_barcodes(cPath, &cOptions, &count)
+	var cErr C.ZXingError
+	results := C.decode_barcodes_pixels((*C.uchar)(unsafe.Pointer(&data[0])),
+		C.int(width), C.int(height), C.int(channels), &cOptions, &count, &cErr)
 	if results == nil {
-		return nil, fmt.Errorf("%s", C.GoString(C.get_last_error()))
+		return nil, newNativeError(&cErr)
 	}
 	defer C.free_results(results, count)
 
+	return newCGODecodeResults(results, count), nil
+}
+
+// CGOEncodeOptions represents CGO encode options.
+type CGOEncodeOptions struct {
+	Format  Format
+	ECLevel string
+}
+
+// CGOEncodeResult is an encoded symbol as a module matrix without quiet zone.
+// Each byte of Modules is 1 for a dark module and 0 for a light one.
+type CGOEncodeResult struct {
+	Modules []byte
+	Width   int
+	Height  int
+}
+
+// Encode encodes text into a barcode module matrix.
+func Encode(text string, options *CGOEncodeOptions) (*CGOEncodeResult, error) {
+	if options == nil {
+		options = &CGOEncodeOptions{Format: FormatQRCode}
+	}
+
+	cOptions := C.create_encode_options()
+	if cOptions == nil {
+		return nil, fmt.Errorf("failed to create encode options")
+	}
+	defer C.free_encode_options(cOptions)
+
+	var cECLevel *C.char
+	if options.ECLevel != "" {
+		cECLevel = C.CString(options.ECLevel)
+		defer C.free(unsafe.Pointer(cECLevel))
+	}
+	var cErr C.ZXingError
+	if C.configure_encode_options(cOptions, C.int(options.Format), cECLevel, &cErr) != 0 {
+		return nil, newNativeError(&cErr)
+	}
+
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+
+	result := C.encode_barcode(cText, C.int(len(text)), cOptions, &cErr)
+	if result == nil {
+		return nil, newNativeError(&cErr)
+	}
+	defer C.free_encode_result(result)
+
+	return &CGOEncodeResult{
+		Modules: C.GoBytes(unsafe.Pointer(result.modules), result.width*result.height),
+		Width:   int(result.width),
+		Height:  int(result.height),
+	}, nil
+}
+
+// newCGODecodeResult copies a C decode result into Go memory.
+func newCGODecodeResult(result *C.DecodeResult) *CGODecodeResult {
+	return &CGODecodeResult{
+		Text:       C.GoStringN(result.text, result.text_length),
+		Format:     Format(result.format),
+		Confidence: float32(result.confidence),
+		Points: []image.Point{
+			{X: int(result.x1), Y: int(result.y1)},
+			{X: int(result.x2), Y: int(result.y2)},
+			{X: int(result.x3), Y: int(result.y3)},
+			{X: int(result.x4), Y: int(result.y4)},
+		},
+		SymbologyIdentifier: C.GoString(result.symbology_identifier),
+		ECLevel:             C.GoString(result.ec_level),
+		Version:             C.GoString(result.version),
+		Orientation:         int(result.orientation),
+		IsMirrored:          result.is_mirrored != 0,
+		IsInverted:          result.is_inverted != 0,
+		LineCount:           int(result.line_count),
+		ContentType:         int(result.content_type),
+		HasECI:              result.has_eci != 0,
+		Bytes:               C.GoBytes(unsafe.Pointer(result.bytes), result.bytes_length),
+		BytesECI:            C.GoBytes(unsafe.Pointer(result.bytes_eci), result.bytes_eci_length),
+		SequenceSize:        int(result.sequence_size),
+		SequenceIndex:       int(result.sequence_index),
+		SequenceID:          C.GoString(result.sequence_id),
+		ErrorType:           int(result.error_type),
+		ErrorMessage:        C.GoString(result.error_message),
+		AddOn:               C.GoString(result.ean_add_on),
+	}
+}
+
+// newCGODecodeResults copies a C result array into Go memory.
+func newCGODecodeResults(results **C.DecodeResult, count C.int) []*CGODecodeResult {
 	goResults := make([]*CGODecodeResult, int(count))
 	for i := 0; i < int(count); i++ {
 		result := C.decode_result_get(results, C.int(i))
@@ -185,14 +320,61 @@ func DecodeMulti(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResul
 			continue
 		}
 
-		goResults[i] = &CGODecodeResult{
-			Text:       C.GoString(result.text),
-			Format:     BarcodeFormat(result.format),
-			Confidence: float32(result.confidence),
+		goResults[i] = newCGODecodeResult(result)
+	}
+	return goResults
+}
+
+// newCDecodeOptions converts CGODecodeOptions to the C DecodeOptions struct.
+func newCDecodeOptions(options *CGODecodeOptions) (C.DecodeOptions, error) {
+	cOptions := C.DecodeOptions{
+		formats:               C.int(options.Formats),
+		try_harder:            C.int(boolToInt(options.TryHarder)),
+		try_rotate:            C.int(boolToInt(options.TryRotate)),
+		try_invert:            C.int(boolToInt(options.TryInvert)),
+		try_downscale:         C.int(boolToInt(options.TryDownscale)),
+		binarizer:             C.int(options.Binarizer),
+		downscale_threshold:   C.int(options.DownscaleThreshold),
+		downscale_factor:      C.int(options.DownscaleFactor),
+		is_pure:               C.int(boolToInt(options.IsPure)),
+		min_line_count:        C.int(options.MinLineCount),
+		max_number_of_symbols: C.int(options.MaxSymbols),
+		return_errors:         C.int(boolToInt(options.ReturnErrors)),
+		text_mode:             C.int(options.TextMode),
+		ean_add_on:            C.int(options.EANAddOn),
+		code39_extended:       C.int(boolToInt(options.Code39Extended)),
+		code39_check_digit:    C.int(boolToInt(options.Code39CheckDigit)),
+		itf_check_digit:       C.int(boolToInt(options.ITFCheckDigit)),
+		itf_min_length:        C.int(options.ITFMinLength),
+		itf_max_length:        C.int(options.ITFMaxLength),
+		codabar_start_stop:    C.int(boolToInt(options.CodabarStartStop)),
+	}
+	if options.CharacterSet != "" {
+		cCharset := C.CString(options.CharacterSet)
+		defer C.free(unsafe.Pointer(cCharset))
+		var cErr C.ZXingError
+		if C.configure_decode_charset(&cOptions, cCharset, &cErr) != 0 {
+			return cOptions, newNativeError(&cErr)
 		}
 	}
+	return cOptions, nil
+}
 
-	return goResults, nil
+// CheckABI reports an error when libzxingwrapper.a was built from a zxing.h
+// with another ZXING_ABI_VERSION, whose structs Go would read with the wrong layout.
+func CheckABI() error {
+	if got, want := int(C.zxing_abi_version()), int(C.ZXING_ABI_VERSION); got != want {
+		return fmt.Errorf("lib/windows-x64/libzxingwrapper.a has ABI version %d, include/zxing.h expects %d; rebuild it with 'go run ./cmd/build build-lib'", got, want)
+	}
+	return nil
+}
+
+// newNativeError converts the per-call error filled in by the C wrapper.
+func newNativeError(cErr *C.ZXingError) error {
+	return &nativeError{
+		code: int(cErr.code),
+		msg:  C.GoString(&cErr.message[0]),
+	}
 }
 
 // boolToInt converts a bool to an int (1 or 0).
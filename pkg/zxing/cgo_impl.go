@@ -10,8 +10,6 @@ import (
 	"context"
 	"fmt"
 	"image"
-	"image/png"
-	"os"
 )
 
 // cgoAvailable indicates that CGO is enabled on this platform.
@@ -21,134 +19,250 @@ const cgoAvailable = true
 // cgoZXing implements the ZXing interface using CGO.
 type cgoZXing struct {
 	config *Config
+	// slots bounds the native calls in flight to Config.Concurrency.
+	slots chan struct{}
+}
+
+// newCGOZXing creates a CGO backend instance for config.
+func newCGOZXing(config *Config) *cgoZXing {
+	return &cgoZXing{
+		config: config,
+		slots:  make(chan struct{}, concurrency(config)),
+	}
 }
 
 // DecodeImage decodes an image using the CGO backend.
 func (c *cgoZXing) DecodeImage(ctx context.Context, img image.Image, opts *DecodeOptions) (*Result, error) {
-	if opts == nil {
-		opts = &DecodeOptions{}
-	}
-
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Convert image to RGBA byte data
-	data := make([]byte, width*height*4)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			idx := (y*width + x) * 4
-			data[idx] = uint8(r >> 8)
-			data[idx+1] = uint8(g >> 8)
-			data[idx+2] = uint8(b >> 8)
-			data[idx+3] = uint8(a >> 8)
-		}
+	ctx, cancel := withTimeout(ctx, c.config)
+	defer cancel()
+
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	result, err := c.decodePixels(ctx, data, width, height, 1, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return c.DecodeBytes(ctx, data, width, height, opts)
+	offsetPoints([]*Result{result}, img.Bounds().Min)
+	return result, nil
 }
 
 // DecodeBytes decodes raw RGBA byte data using the CGO backend.
 func (c *cgoZXing) DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	if len(data) == 0 {
-		return nil, fmt.Errorf("empty image data")
+	ctx, cancel := withTimeout(ctx, c.config)
+	defer cancel()
+
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	result, err := c.decodePixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints([]*Result{result}, rect.Min)
+	return result, nil
+}
+
+// decodePixels decodes validated pixel data with the given channel count.
+func (c *cgoZXing) decodePixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) (*Result, error) {
+	if err := validateDecodeOptions(opts); err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
 	}
 
 	if opts == nil {
-		opts = &DecodeOptions{}
+		opts = DefaultDecodeOptions()
 	}
 
-	// Create a temporary PNG file for CGO decode (C API reads from file path)
-	tempFile, err := os.CreateTemp("", "zxing_*.png")
+	cgoOpts, err := toCGOOptions(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, wrapError(ctx, BackendCGO, "decode", err)
 	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
 
-	// Encode RGBA data to PNG
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	if len(data) >= len(img.Pix) {
-		copy(img.Pix, data)
-	} else {
-		return nil, fmt.Errorf("image data size mismatch")
+	result, err := runLimited(ctx, c.slots, func() (*CGODecodeResult, error) {
+		return DecodePixels(data, width, height, channels, cgoOpts)
+	})
+	if err != nil {
+		return nil, wrapError(ctx, BackendCGO, "decode", err)
+	}
+
+	return convertCGOResult(result), nil
+}
+
+// DecodeMultiImage decodes every barcode in an image using the CGO backend.
+func (c *cgoZXing) DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, c.config)
+	defer cancel()
+
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	results, err := c.decodeMultiPixels(ctx, data, width, height, 1, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints(results, img.Bounds().Min)
+	return results, nil
+}
+
+// DecodeMultiBytes decodes every barcode in raw RGBA byte data using the CGO backend.
+func (c *cgoZXing) DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, c.config)
+	defer cancel()
+
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
 	}
 
-	if err := png.Encode(tempFile, img); err != nil {
-		return nil, fmt.Errorf("failed to encode image: %w", err)
+	results, err := c.decodeMultiPixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
 	}
-	tempFile.Close()
 
-	// Build CGO options
+	offsetPoints(results, rect.Min)
+	return results, nil
+}
+
+// decodeMultiPixels decodes every barcode in validated pixel data with the given channel count.
+func (c *cgoZXing) decodeMultiPixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) ([]*Result, error) {
+	if err := validateDecodeOptions(opts); err != nil {
+		return nil, newError(BackendCGO, "decode", ErrInvalidInput, err)
+	}
+
+	if opts == nil {
+		opts = DefaultDecodeOptions()
+	}
+
+	cgoOpts, err := toCGOOptions(opts)
+	if err != nil {
+		return nil, wrapError(ctx, BackendCGO, "decode", err)
+	}
+
+	cgoResults, err := runLimited(ctx, c.slots, func() ([]*CGODecodeResult, error) {
+		return DecodeMultiPixels(data, width, height, channels, cgoOpts)
+	})
+	if err != nil {
+		return nil, wrapError(ctx, BackendCGO, "decode", err)
+	}
+
+	results := make([]*Result, 0, len(cgoResults))
+	for _, result := range cgoResults {
+		if result != nil {
+			results = append(results, convertCGOResult(result))
+		}
+	}
+
+	return limitResults(results, opts), nil
+}
+
+// toCGOOptions converts public DecodeOptions to CGODecodeOptions.
+func toCGOOptions(opts *DecodeOptions) (*CGODecodeOptions, error) {
 	cgoOpts := NewDefaultOptions()
 	if cgoOpts == nil {
 		return nil, fmt.Errorf("failed to create CGO options")
 	}
+	cgoOpts.Formats = possibleFormats(opts)
 	cgoOpts.TryHarder = opts.TryHarder
-	if len(opts.PossibleFormats) > 0 {
-		formatFlags := FormatNone
-		for _, format := range opts.PossibleFormats {
-			switch format {
-			case "QR_CODE", "QRCode":
-				formatFlags |= FormatQRCode
-			case "AZTEC":
-				formatFlags |= FormatAztec
-			case "DATA_MATRIX", "DataMatrix":
-				formatFlags |= FormatDataMatrix
-			case "PDF_417", "PDF417":
-				formatFlags |= FormatPDF417
-			case "CODE_128", "Code128":
-				formatFlags |= FormatCode128
-			case "CODE_39", "Code39":
-				formatFlags |= FormatCode39
-			case "CODE_93", "Code93":
-				formatFlags |= FormatCode93
-			case "EAN_8", "EAN8":
-				formatFlags |= FormatEAN8
-			case "EAN_13", "EAN13":
-				formatFlags |= FormatEAN13
-			case "UPC_A", "UPCA":
-				formatFlags |= FormatUPCA
-			case "UPC_E", "UPCE":
-				formatFlags |= FormatUPCE
-			case "ITF":
-				formatFlags |= FormatITF
-			case "CODABAR":
-				formatFlags |= FormatCodabar
-			case "MAXICODE":
-				formatFlags |= FormatMaxiCode
-			}
-		}
-		if formatFlags == FormatNone {
-			cgoOpts.Formats = FormatAll
-		} else {
-			cgoOpts.Formats = formatFlags
-		}
-	} else {
-		cgoOpts.Formats = FormatAll
-	}
-
-	// Call CGO decode
-	result, err := Decode(tempFile.Name(), cgoOpts)
+	cgoOpts.TryRotate = tryRotate(opts)
+	cgoOpts.TryInvert = opts.TryInvert
+	cgoOpts.TryDownscale = tryDownscale(opts)
+	cgoOpts.Binarizer = opts.Binarizer
+	cgoOpts.DownscaleThreshold = opts.DownscaleThreshold
+	cgoOpts.DownscaleFactor = opts.DownscaleFactor
+	cgoOpts.IsPure = opts.IsPure
+	cgoOpts.MinLineCount = opts.MinLineCount
+	cgoOpts.MaxSymbols = opts.MaxSymbols
+	cgoOpts.ReturnErrors = opts.ReturnErrors
+	cgoOpts.TextMode = opts.TextMode
+	charset, err := canonicalCharacterSet(opts.CharacterSet)
 	if err != nil {
 		return nil, err
 	}
+	cgoOpts.CharacterSet = charset
+	cgoOpts.EANAddOn = opts.EANAddOn
+	if sym := opts.Symbology; sym != nil {
+		cgoOpts.Code39Extended = code39ExtendedMode(sym)
+		cgoOpts.Code39CheckDigit = sym.Code39CheckDigit
+		cgoOpts.ITFCheckDigit = sym.ITFCheckDigit
+		cgoOpts.ITFMinLength = sym.ITFMinLength
+		cgoOpts.ITFMaxLength = sym.ITFMaxLength
+		cgoOpts.CodabarStartStop = codabarStartStop(sym)
+	}
+	return cgoOpts, nil
+}
 
+// convertCGOResult converts a CGODecodeResult to the public Result type.
+func convertCGOResult(result *CGODecodeResult) *Result {
 	return &Result{
 		Text:   result.Text,
-		Format: result.Format.String(),
-		Points: []image.Point{},
+		Format: result.Format,
+		Points: result.Points,
+
+		SymbologyIdentifier: result.SymbologyIdentifier,
+		ECLevel:             result.ECLevel,
+		Version:             result.Version,
+		Orientation:         result.Orientation,
+		IsMirrored:          result.IsMirrored,
+		IsInverted:          result.IsInverted,
+		LineCount:           result.LineCount,
+		ContentType:         ContentType(result.ContentType),
+		HasECI:              result.HasECI,
+		Bytes:               result.Bytes,
+		ECISegments:         parseECISegments(result.BytesECI, len(result.SymbologyIdentifier)),
+		StructuredAppend:    newStructuredAppend(result.SequenceSize, result.SequenceIndex, result.SequenceID),
+		Error:               symbolError(BackendCGO, result.ErrorType, result.ErrorMessage),
+		AddOn:               result.AddOn,
 		Metadata: map[string]interface{}{
-			"confidence": result.Confidence,
-			"backend":    "cgo",
+			"backend": "cgo",
 		},
-	}, nil
+	}
 }
 
 // EncodeText encodes text to a barcode image using the CGO backend.
 func (c *cgoZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
-	return nil, fmt.Errorf("encoding is not supported by the CGO backend")
+	ctx, cancel := withTimeout(ctx, c.config)
+	defer cancel()
+
+	if len(text) == 0 {
+		return nil, newError(BackendCGO, "encode", ErrInvalidInput, fmt.Errorf("empty text"))
+	}
+
+	if opts == nil {
+		opts = defaultEncodeOptions()
+	}
+
+	format, ecLevel, err := encodeParams(opts)
+	if err != nil {
+		return nil, newError(BackendCGO, "encode", ErrInvalidInput, err)
+	}
+
+	symbol, err := runLimited(ctx, c.slots, func() (*CGOEncodeResult, error) {
+		return Encode(text, &CGOEncodeOptions{Format: format, ECLevel: ecLevel})
+	})
+	if err != nil {
+		return nil, wrapError(ctx, BackendCGO, "encode", err)
+	}
+
+	return renderSymbol(symbol.Modules, symbol.Width, symbol.Height, format, opts), nil
 }
 
 // EncodeToBytes encodes text to raw byte data using the CGO backend.
@@ -158,22 +272,7 @@ func (c *cgoZXing) EncodeToBytes(ctx context.Context, text string, opts *EncodeO
 		return nil, 0, 0, err
 	}
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	data := make([]byte, width*height*4)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			idx := (y*width + x) * 4
-			data[idx] = uint8(r >> 8)
-			data[idx+1] = uint8(g >> 8)
-			data[idx+2] = uint8(b >> 8)
-			data[idx+3] = uint8(a >> 8)
-		}
-	}
-
+	data, width, height := imageToRGBA(img)
 	return data, width, height, nil
 }
 
@@ -186,19 +285,3 @@ func (c *cgoZXing) Close() error {
 func (c *cgoZXing) GetBackend() Backend {
 	return BackendCGO
 }
-
-// decodeWithCGOImpl is called by universal_impl to delegate to CGO backend.
-func decodeWithCGOImpl(ctx context.Context, config *Config, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	cgoImpl := &cgoZXing{
-		config: config,
-	}
-	return cgoImpl.DecodeBytes(ctx, data, width, height, opts)
-}
-
-// encodeWithCGOImpl is called by universal_impl to delegate to CGO backend.
-func encodeWithCGOImpl(ctx context.Context, config *Config, text string, opts *EncodeOptions) (image.Image, error) {
-	cgoImpl := &cgoZXing{
-		config: config,
-	}
-	return cgoImpl.EncodeText(ctx, text, opts)
-}
@@ -7,7 +7,7 @@ package zxing
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"image"
 )
 
@@ -15,97 +15,86 @@ import (
 // Used by factory.go for compile-time backend selection.
 const cgoAvailable = false
 
-// BarcodeFormat represents the barcode format type (stub values for non-CGO builds).
-type BarcodeFormat int
-
-const (
-	FormatNone       BarcodeFormat = 0
-	FormatQRCode     BarcodeFormat = 1
-	FormatAztec      BarcodeFormat = 2
-	FormatCodabar    BarcodeFormat = 4
-	FormatCode39     BarcodeFormat = 8
-	FormatCode93     BarcodeFormat = 16
-	FormatCode128    BarcodeFormat = 32
-	FormatDataMatrix BarcodeFormat = 64
-	FormatEAN8       BarcodeFormat = 128
-	FormatEAN13      BarcodeFormat = 256
-	FormatITF        BarcodeFormat = 512
-	FormatMaxiCode   BarcodeFormat = 1024
-	FormatPDF417     BarcodeFormat = 2048
-	FormatUPCA       BarcodeFormat = 4096
-	FormatUPCE       BarcodeFormat = 8192
-	FormatAll        BarcodeFormat = 0xFFFF
-)
-
-// String returns the string representation of the barcode format.
-func (f BarcodeFormat) String() string {
-	switch f {
-	case FormatNone:
-		return "None"
-	case FormatQRCode:
-		return "QR Code"
-	case FormatAztec:
-		return "Aztec"
-	case FormatCodabar:
-		return "Codabar"
-	case FormatCode39:
-		return "Code 39"
-	case FormatCode93:
-		return "Code 93"
-	case FormatCode128:
-		return "Code 128"
-	case FormatDataMatrix:
-		return "Data Matrix"
-	case FormatEAN8:
-		return "EAN-8"
-	case FormatEAN13:
-		return "EAN-13"
-	case FormatITF:
-		return "ITF"
-	case FormatMaxiCode:
-		return "MaxiCode"
-	case FormatPDF417:
-		return "PDF417"
-	case FormatUPCA:
-		return "UPC-A"
-	case FormatUPCE:
-		return "UPC-E"
-	case FormatAll:
-		return "All"
-	default:
-		return fmt.Sprintf("Unknown(%d)", f)
-	}
-}
-
 // CGODecodeOptions represents CGO decode options (stub for non-CGO builds).
 type CGODecodeOptions struct {
-	Formats      BarcodeFormat
+	Formats      Format
 	TryHarder    bool
 	TryRotate    bool
 	TryInvert    bool
 	TryDownscale bool
+
+	Binarizer          Binarizer
+	DownscaleThreshold int
+	DownscaleFactor    int
+	IsPure             bool
+	MinLineCount       int
+	MaxSymbols         int
+	ReturnErrors       bool
+	TextMode           TextMode
+	CharacterSet       string
+	EANAddOn           EANAddOn
+
+	Code39Extended   bool
+	Code39CheckDigit bool
+	ITFCheckDigit    bool
+	ITFMinLength     int
+	ITFMaxLength     int
+	CodabarStartStop bool
 }
 
 // CGODecodeResult represents a CGO decode result (stub for non-CGO builds).
 type CGODecodeResult struct {
 	Text       string
-	Format     BarcodeFormat
+	Format     Format
 	Confidence float32
 }
 
+// CGOEncodeOptions represents CGO encode options (stub for non-CGO builds).
+type CGOEncodeOptions struct {
+	Format  Format
+	ECLevel string
+}
+
+// CGOEncodeResult represents a CGO encode result (stub for non-CGO builds).
+type CGOEncodeResult struct {
+	Modules []byte
+	Width   int
+	Height  int
+}
+
+// Encode returns an error when CGO is not available.
+func Encode(text string, options *CGOEncodeOptions) (*CGOEncodeResult, error) {
+	return nil, cgoUnavailableError("encode")
+}
+
+// CheckABI returns an error when CGO is not available.
+func CheckABI() error {
+	return cgoUnavailableError("init")
+}
+
 // NewDefaultOptions returns nil when CGO is not available.
 func NewDefaultOptions() *CGODecodeOptions {
 	return nil
 }
 
-// Decode returns an error when CGO is not available.
-func Decode(imagePath string, options *CGODecodeOptions) (*CGODecodeResult, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+// DecodeFile returns an error when CGO is not available.
+func DecodeFile(imagePath string, options *CGODecodeOptions) (*CGODecodeResult, error) {
+	return nil, cgoUnavailableError("decode")
+}
+
+// DecodeMultiFile returns an error when CGO is not available.
+func DecodeMultiFile(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
+	return nil, cgoUnavailableError("decode")
 }
 
-// DecodeMulti returns an error when CGO is not available.
-func DecodeMulti(imagePath string, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+// DecodePixels returns an error when CGO is not available.
+func DecodePixels(data []byte, width, height, channels int, options *CGODecodeOptions) (*CGODecodeResult, error) {
+	return nil, cgoUnavailableError("decode")
+}
+
+// DecodeMultiPixels returns an error when CGO is not available.
+func DecodeMultiPixels(data []byte, width, height, channels int, options *CGODecodeOptions) ([]*CGODecodeResult, error) {
+	return nil, cgoUnavailableError("decode")
 }
 
 // boolToInt converts a bool to an int (1 or 0).
@@ -116,39 +105,44 @@ func boolToInt(b bool) int {
 	return 0
 }
 
-// decodeWithCGOImpl provides a stub implementation when CGO is disabled.
-func decodeWithCGOImpl(ctx context.Context, config *Config, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
-}
-
-// encodeWithCGOImpl provides a stub implementation when CGO is disabled.
-func encodeWithCGOImpl(ctx context.Context, config *Config, text string, opts *EncodeOptions) (image.Image, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
-}
-
 // cgoZXing is a stub that returns errors when CGO is not available.
 type cgoZXing struct {
 	config *Config
 }
 
+// newCGOZXing creates a stub CGO backend instance.
+func newCGOZXing(config *Config) *cgoZXing {
+	return &cgoZXing{config: config}
+}
+
 // DecodeImage returns an error when CGO is not available.
 func (c *cgoZXing) DecodeImage(ctx context.Context, img image.Image, opts *DecodeOptions) (*Result, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+	return nil, cgoUnavailableError("decode")
 }
 
 // DecodeBytes returns an error when CGO is not available.
 func (c *cgoZXing) DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+	return nil, cgoUnavailableError("decode")
+}
+
+// DecodeMultiImage returns an error when CGO is not available.
+func (c *cgoZXing) DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error) {
+	return nil, cgoUnavailableError("decode")
+}
+
+// DecodeMultiBytes returns an error when CGO is not available.
+func (c *cgoZXing) DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error) {
+	return nil, cgoUnavailableError("decode")
 }
 
 // EncodeText returns an error when CGO is not available.
 func (c *cgoZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
-	return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+	return nil, cgoUnavailableError("encode")
 }
 
 // EncodeToBytes returns an error when CGO is not available.
 func (c *cgoZXing) EncodeToBytes(ctx context.Context, text string, opts *EncodeOptions) ([]byte, int, int, error) {
-	return nil, 0, 0, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+	return nil, 0, 0, cgoUnavailableError("encode")
 }
 
 // Close is a no-op stub.
@@ -160,3 +154,11 @@ func (c *cgoZXing) Close() error {
 func (c *cgoZXing) GetBackend() Backend {
 	return BackendCGO
 }
+
+// errCGOUnavailable is the cause reported by every stub in this file.
+var errCGOUnavailable = errors.New("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows)")
+
+// cgoUnavailableError returns an ErrBackendUnavailable error for op.
+func cgoUnavailableError(op string) error {
+	return newError(BackendCGO, op, ErrBackendUnavailable, errCGOUnavailable)
+}
@@ -2,6 +2,7 @@ package zxing
 
 import (
 	"os"
+	"runtime"
 	"strconv"
 )
 
@@ -30,8 +31,16 @@ type Config struct {
 	// Debug 是否启用调试模式
 	Debug bool
 
-	// Timeout 操作超时时间（秒）
+	// Timeout 单次解码/编码调用的默认超时时间（秒），仅在传入的 ctx 没有截止时间时生效；
+	// 0 或负数表示不限制，DefaultConfig() 为 30。超时后调用立即返回 ErrCanceled（可用 errors.Is 判断
+	// context.DeadlineExceeded），CGO 后端仍在运行的原生调用会在后台结束并丢弃结果
 	Timeout int
+
+	// Concurrency 同一实例最多并行执行的解码/编码调用数，超出的调用排队并遵守 ctx；0 或负数表示 runtime.GOMAXPROCS(0)
+	Concurrency int
+
+	// CompilationCacheDir WASM 后端（wazero）编译结果的缓存目录，为空表示不缓存
+	CompilationCacheDir string
 }
 
 // DefaultConfig 返回默认配置
@@ -72,5 +81,25 @@ func LoadConfigFromEnv() *Config {
 		}
 	}
 
+	// 读取并发数
+	if concurrency := os.Getenv("ZXING_CONCURRENCY"); concurrency != "" {
+		if concurrencyInt, err := strconv.Atoi(concurrency); err == nil {
+			config.Concurrency = concurrencyInt
+		}
+	}
+
+	// 读取编译缓存目录
+	if cacheDir := os.Getenv("ZXING_COMPILATION_CACHE_DIR"); cacheDir != "" {
+		config.CompilationCacheDir = cacheDir
+	}
+
 	return config
 }
+
+// concurrency returns the effective Config.Concurrency.
+func concurrency(config *Config) int {
+	if config == nil || config.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return config.Concurrency
+}
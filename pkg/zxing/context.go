@@ -0,0 +1,77 @@
+package zxing
+
+import (
+	"context"
+	"time"
+)
+
+// withTimeout applies config.Timeout as the deadline of ctx unless ctx already
+// has one. A nil ctx is treated as context.Background().
+func withTimeout(ctx context.Context, config *Config) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if config == nil || config.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+}
+
+// runWithContext calls fn on its own goroutine and returns its result, or
+// ctx.Err() as soon as ctx is done. A native call cannot be interrupted, so fn
+// keeps running after an early return and its result is dropped; fn must
+// release everything it allocates itself.
+func runWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	return runLimited(ctx, nil, fn)
+}
+
+// runLimited is runWithContext with at most cap(slots) calls of fn running at
+// once among callers sharing slots; a nil slots imposes no limit. Waiting for a
+// slot honors ctx. A slot is only given back when fn returns, so an abandoned
+// call keeps counting against the limit until the native code finishes.
+func runLimited[T any](ctx context.Context, slots chan struct{}, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			<-slots
+			return zero, err
+		}
+		call := fn
+		fn = func() (T, error) {
+			defer func() { <-slots }()
+			return call()
+		}
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	// Buffered so an abandoned worker can always deliver and exit.
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn()
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,359 @@
+package zxing
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// imageToRGBA converts an image to tightly packed RGBA byte data.
+// It backs EncodeToBytes. Pixel (0, 0) of the returned data is img.Bounds().Min.
+func imageToRGBA(img image.Image) ([]byte, int, int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	data := make([]byte, width*height*4)
+	switch m := img.(type) {
+	case *image.RGBA:
+		for y := 0; y < height; y++ {
+			start := m.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(data[y*width*4:(y+1)*width*4], m.Pix[start:start+width*4])
+		}
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			row := m.Pix[m.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			dst := data[y*width*4 : (y+1)*width*4]
+			for x := 0; x < width; x++ {
+				v := row[x]
+				dst[x*4], dst[x*4+1], dst[x*4+2], dst[x*4+3] = v, v, v, 0xff
+			}
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				idx := (y*width + x) * 4
+				data[idx] = uint8(r >> 8)
+				data[idx+1] = uint8(g >> 8)
+				data[idx+2] = uint8(b >> 8)
+				data[idx+3] = uint8(a >> 8)
+			}
+		}
+	}
+
+	return data, width, height
+}
+
+// imageToGray converts an image to tightly packed 8-bit luminance data.
+// It is shared by every backend's DecodeImage and DecodeMultiImage, which hand
+// the result to the reader as a single-channel buffer. Pixel (0, 0) of the
+// returned data is img.Bounds().Min.
+//
+// *image.Gray and the Y plane of *image.YCbCr are returned without copying when
+// their rows are contiguous; *image.RGBA, *image.NRGBA and *image.Paletted are
+// converted straight from Pix. Any other type falls back to img.At.
+func imageToGray(img image.Image) ([]byte, int, int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, width, height
+	}
+
+	switch m := img.(type) {
+	case *image.Gray:
+		start := m.PixOffset(bounds.Min.X, bounds.Min.Y)
+		return planeRows(m.Pix[start:], m.Stride, width, height), width, height
+	case *image.YCbCr:
+		start := m.YOffset(bounds.Min.X, bounds.Min.Y)
+		return planeRows(m.Y[start:], m.YStride, width, height), width, height
+	}
+
+	data := make([]byte, width*height)
+	switch m := img.(type) {
+	case *image.RGBA:
+		for y := 0; y < height; y++ {
+			row := m.Pix[m.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			dst := data[y*width : (y+1)*width]
+			for x := range dst {
+				dst[x] = luminance(row[x*4], row[x*4+1], row[x*4+2])
+			}
+		}
+	case *image.NRGBA:
+		// Premultiply so transparent pixels match the generic img.At path.
+		for y := 0; y < height; y++ {
+			row := m.Pix[m.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			dst := data[y*width : (y+1)*width]
+			for x := range dst {
+				c := color.NRGBA{R: row[x*4], G: row[x*4+1], B: row[x*4+2], A: row[x*4+3]}
+				r, g, b, _ := c.RGBA()
+				dst[x] = luminance(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			}
+		}
+	case *image.Paletted:
+		var lut [256]uint8
+		for i, c := range m.Palette {
+			r, g, b, _ := c.RGBA()
+			lut[i] = luminance(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+		}
+		for y := 0; y < height; y++ {
+			row := m.Pix[m.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			dst := data[y*width : (y+1)*width]
+			for x := range dst {
+				dst[x] = lut[row[x]]
+			}
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				data[y*width+x] = luminance(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			}
+		}
+	}
+
+	return data, width, height
+}
+
+// planeRows returns height rows of width bytes from an 8-bit plane whose rows
+// are stride bytes apart. The plane is returned as is when rows are contiguous.
+func planeRows(pix []byte, stride, width, height int) []byte {
+	if stride == width {
+		return pix[:width*height]
+	}
+	data := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		copy(data[y*width:(y+1)*width], pix[y*stride:y*stride+width])
+	}
+	return data
+}
+
+// luminance matches the RGB to gray conversion zxing-cpp applies to
+// multi-channel input, so a gray buffer decodes exactly like its RGBA source.
+func luminance(r, g, b uint8) uint8 {
+	return uint8((306*uint32(r) + 601*uint32(g) + 117*uint32(b) + 0x200) >> 10)
+}
+
+// regionImage restricts img to opts.Region without copying pixels.
+// The zero Region selects the whole image.
+func regionImage(img image.Image, opts *DecodeOptions) (image.Image, error) {
+	if opts == nil || opts.Region == (image.Rectangle{}) {
+		return img, nil
+	}
+
+	rect := opts.Region.Intersect(img.Bounds())
+	if rect.Empty() {
+		return nil, fmt.Errorf("region %v does not overlap image bounds %v", opts.Region, img.Bounds())
+	}
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect), nil
+	}
+	return regionView{Image: img, rect: rect}, nil
+}
+
+// regionView narrows the bounds of an image type that has no SubImage method.
+type regionView struct {
+	image.Image
+	rect image.Rectangle
+}
+
+func (v regionView) Bounds() image.Rectangle { return v.rect }
+
+// rgbaRegion validates RGBA data whose rows are opts.Stride bytes apart and
+// returns the tightly packed pixels of opts.Region together with the region
+// rectangle in buffer coordinates. Rows are only copied when the region is
+// not already contiguous in data.
+func rgbaRegion(data []byte, width, height int, opts *DecodeOptions) ([]byte, image.Rectangle, error) {
+	if len(data) == 0 {
+		return nil, image.Rectangle{}, fmt.Errorf("empty image data")
+	}
+	if width <= 0 || height <= 0 {
+		return nil, image.Rectangle{}, fmt.Errorf("invalid dimensions: width=%d, height=%d", width, height)
+	}
+	if width > (1<<30)/height/4 {
+		return nil, image.Rectangle{}, fmt.Errorf("image dimensions overflow: %dx%d", width, height)
+	}
+
+	stride := width * 4
+	rect := image.Rect(0, 0, width, height)
+	if opts != nil {
+		if opts.Stride != 0 {
+			stride = opts.Stride
+		}
+		if opts.Region != (image.Rectangle{}) {
+			rect = opts.Region.Intersect(rect)
+			if rect.Empty() {
+				return nil, image.Rectangle{}, fmt.Errorf("region %v does not overlap image bounds %dx%d", opts.Region, width, height)
+			}
+		}
+	}
+	if stride < width*4 || stride > (1<<30)/height {
+		return nil, image.Rectangle{}, fmt.Errorf("invalid stride %d for width %d", stride, width)
+	}
+	if need := stride*(height-1) + width*4; len(data) < need {
+		return nil, image.Rectangle{}, fmt.Errorf("data too short: have %d bytes, need %d", len(data), need)
+	}
+
+	rowBytes := rect.Dx() * 4
+	start := rect.Min.Y*stride + rect.Min.X*4
+	if stride == rowBytes {
+		return data[start : start+rowBytes*rect.Dy()], rect, nil
+	}
+	pixels := make([]byte, rowBytes*rect.Dy())
+	for y := 0; y < rect.Dy(); y++ {
+		copy(pixels[y*rowBytes:(y+1)*rowBytes], data[start+y*stride:])
+	}
+	return pixels, rect, nil
+}
+
+// validatePixels checks that data holds width x height tightly packed pixels of
+// the given channel count.
+func validatePixels(data []byte, width, height, channels int) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty image data")
+	}
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid dimensions: width=%d, height=%d", width, height)
+	}
+	if width > (1<<30)/height/channels {
+		return fmt.Errorf("image dimensions overflow: %dx%d", width, height)
+	}
+	if need := width * height * channels; len(data) < need {
+		return fmt.Errorf("data too short: have %d bytes, need %d", len(data), need)
+	}
+	return nil
+}
+
+// validateDecodeOptions checks the reader tuning fields of opts. A nil opts is valid.
+func validateDecodeOptions(opts *DecodeOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.Stride < 0 {
+		return fmt.Errorf("invalid stride: %d", opts.Stride)
+	}
+	if opts.Binarizer < BinarizerLocalAverage || opts.Binarizer > BinarizerBoolCast {
+		return fmt.Errorf("invalid binarizer: %s", opts.Binarizer)
+	}
+	if opts.TextMode < TextModeHRI || opts.TextMode > TextModeEscaped {
+		return fmt.Errorf("invalid text mode: %s", opts.TextMode)
+	}
+	if opts.EANAddOn < EANAddOnIgnore || opts.EANAddOn > EANAddOnRequire {
+		return fmt.Errorf("invalid EAN add-on mode: %s", opts.EANAddOn)
+	}
+	if _, err := canonicalCharacterSet(opts.CharacterSet); err != nil {
+		return err
+	}
+	if sym := opts.Symbology; sym != nil {
+		if sym.ITFMinLength < 0 || sym.ITFMaxLength < 0 ||
+			(sym.ITFMaxLength > 0 && sym.ITFMaxLength < sym.ITFMinLength) {
+			return fmt.Errorf("invalid ITF length range: %d-%d", sym.ITFMinLength, sym.ITFMaxLength)
+		}
+	}
+	if opts.DownscaleFactor != 0 && (opts.DownscaleFactor < 2 || opts.DownscaleFactor > 4) {
+		return fmt.Errorf("invalid downscale factor: %d (must be 2, 3 or 4)", opts.DownscaleFactor)
+	}
+	if opts.DownscaleThreshold < 0 || opts.MinLineCount < 0 || opts.MaxSymbols < 0 {
+		return fmt.Errorf("decode options must not be negative")
+	}
+	return nil
+}
+
+// characterSets are the fallback character sets supported by zxing-cpp, by
+// canonical name.
+var characterSets = []string{
+	"ASCII", "ISO-8859-1", "ISO-8859-2", "ISO-8859-3", "ISO-8859-4", "ISO-8859-5",
+	"ISO-8859-6", "ISO-8859-7", "ISO-8859-8", "ISO-8859-9", "ISO-8859-10", "ISO-8859-11",
+	"ISO-8859-13", "ISO-8859-14", "ISO-8859-15", "ISO-8859-16", "Cp437",
+	"windows-1250", "windows-1251", "windows-1252", "windows-1256",
+	"Shift_JIS", "Big5", "GB2312", "GB18030", "EUC-JP", "EUC-KR",
+	"UTF-8", "UTF-16BE", "UTF-16LE", "UTF-32BE", "UTF-32LE", "BINARY",
+}
+
+// characterSetAliases maps other common names (normalized) to a canonical name.
+var characterSetAliases = map[string]string{
+	"USASCII":    "ASCII",
+	"LATIN1":     "ISO-8859-1",
+	"CP1250":     "windows-1250",
+	"CP1251":     "windows-1251",
+	"CP1252":     "windows-1252",
+	"CP1256":     "windows-1256",
+	"SJIS":       "Shift_JIS",
+	"EUCCN":      "GB2312",
+	"GBK":        "GB18030",
+	"UTF16":      "UTF-16BE",
+	"UNICODEBIG": "UTF-16BE",
+}
+
+// canonicalCharacterSet returns the canonical name of a supported character set.
+// Matching ignores case, '-', '_' and spaces; an empty name means auto-detection.
+func canonicalCharacterSet(name string) (string, error) {
+	key := normalizeFormatName(name)
+	if key == "" {
+		return "", nil
+	}
+	for _, cs := range characterSets {
+		if normalizeFormatName(cs) == key {
+			return cs, nil
+		}
+	}
+	if cs, ok := characterSetAliases[key]; ok {
+		return cs, nil
+	}
+	return "", fmt.Errorf("unsupported character set %q", name)
+}
+
+// possibleFormats returns the formats to search for, defaulting to FormatAll.
+func possibleFormats(opts *DecodeOptions) Format {
+	if opts == nil || opts.PossibleFormats == FormatNone {
+		return FormatAll
+	}
+	return opts.PossibleFormats
+}
+
+// tryRotate reports whether opts enables rotation; nil leaves it on.
+func tryRotate(opts *DecodeOptions) bool {
+	return opts.TryRotate == nil || *opts.TryRotate
+}
+
+// tryDownscale reports whether opts enables downscaling; nil leaves it on.
+func tryDownscale(opts *DecodeOptions) bool {
+	return opts.TryDownscale == nil || *opts.TryDownscale
+}
+
+// code39ExtendedMode reports whether sym enables Code 39 Full ASCII mode; nil leaves it on.
+func code39ExtendedMode(sym *SymbologyOptions) bool {
+	return sym.Code39ExtendedMode == nil || *sym.Code39ExtendedMode
+}
+
+// codabarStartStop reports whether sym keeps Codabar start/stop characters; nil leaves it on.
+func codabarStartStop(sym *SymbologyOptions) bool {
+	return sym.CodabarStartStop == nil || *sym.CodabarStartStop
+}
+
+// limitResults truncates results to opts.MaxSymbols when a limit is set.
+func limitResults(results []*Result, opts *DecodeOptions) []*Result {
+	if opts == nil || opts.MaxSymbols <= 0 || len(results) <= opts.MaxSymbols {
+		return results
+	}
+	return results[:opts.MaxSymbols]
+}
+
+// offsetPoints translates result points from buffer coordinates to the
+// coordinate space of the source image, whose bounds start at origin.
+func offsetPoints(results []*Result, origin image.Point) {
+	if origin == (image.Point{}) {
+		return
+	}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		for i := range result.Points {
+			result.Points[i] = result.Points[i].Add(origin)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package zxing
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	// defaultEncodeSize is the image size used when EncodeOptions is nil.
+	defaultEncodeSize = 256
+
+	// defaultLinearHeight is the bar height used for linear codes when no height is given.
+	defaultLinearHeight = 50
+
+	// Quiet zones, in modules, applied when EncodeOptions.Margin is 0.
+	matrixQuietZone = 4
+	linearQuietZone = 10
+)
+
+// linearFormats are rendered with bars stretched to the requested height.
+const linearFormats = FormatCodabar | FormatCode39 | FormatCode93 | FormatCode128 |
+	FormatEAN8 | FormatEAN13 | FormatITF | FormatUPCA | FormatUPCE |
+	FormatDataBar | FormatDataBarExpanded | FormatDataBarLimited
+
+// defaultEncodeOptions returns the options used when EncodeOptions is nil.
+func defaultEncodeOptions() *EncodeOptions {
+	return &EncodeOptions{
+		Width:  defaultEncodeSize,
+		Height: defaultEncodeSize,
+		Format: FormatQRCode,
+	}
+}
+
+// encodeParams validates opts and returns the format and EC level for the writer.
+// An unset format defaults to QR Code.
+func encodeParams(opts *EncodeOptions) (Format, string, error) {
+	format := opts.Format
+	if format == FormatNone {
+		format = FormatQRCode
+	}
+	if f := format.Formats(); len(f) != 1 || f[0] != format {
+		return FormatNone, "", fmt.Errorf("encode format must be a single barcode format, got %s", format)
+	}
+	if opts.Width < 0 || opts.Height < 0 {
+		return FormatNone, "", fmt.Errorf("invalid encode size: %dx%d", opts.Width, opts.Height)
+	}
+
+	ecLevel := strings.ToUpper(strings.TrimSpace(opts.ErrorCorrectionLevel))
+	if ecLevel != "" && (format == FormatQRCode || format == FormatMicroQRCode || format == FormatRMQRCode) {
+		switch ecLevel {
+		case "L", "M", "Q", "H":
+		default:
+			return FormatNone, "", fmt.Errorf("invalid QR error correction level %q (want L, M, Q or H)", opts.ErrorCorrectionLevel)
+		}
+	}
+	return format, ecLevel, nil
+}
+
+// renderSymbol scales a module matrix (one byte per module, non-zero = dark) into
+// a grayscale image. Modules are scaled by whole pixels so edges stay crisp; the
+// image is at least opts.Width x opts.Height with the symbol centered.
+// A positive Margin is a quiet zone in pixels, a negative Margin disables the
+// quiet zone, and 0 applies the default quiet zone for the symbology.
+func renderSymbol(modules []byte, cols, rows int, format Format, opts *EncodeOptions) *image.Gray {
+	linear := linearFormats&format != 0
+
+	quietModules := matrixQuietZone
+	if linear {
+		quietModules = linearQuietZone
+	}
+
+	// Horizontal module size
+	margin := opts.Margin
+	var scaleX int
+	switch {
+	case margin == 0:
+		scaleX = opts.Width / (cols + 2*quietModules)
+	case margin < 0:
+		margin = 0
+		scaleX = opts.Width / cols
+	default:
+		scaleX = (opts.Width - 2*margin) / cols
+	}
+	scaleX = max(scaleX, 1)
+
+	// Vertical module size
+	var scaleY int
+	if linear {
+		barHeight := opts.Height
+		if opts.Margin == 0 {
+			barHeight -= 2 * quietModules * scaleX
+		} else {
+			barHeight -= 2 * margin
+		}
+		if barHeight <= 0 {
+			barHeight = defaultLinearHeight
+		}
+		scaleY = max(barHeight/rows, 1)
+	} else {
+		var fitY int
+		if opts.Margin == 0 {
+			fitY = opts.Height / (rows + 2*quietModules)
+		} else {
+			fitY = (opts.Height - 2*margin) / rows
+		}
+		if opts.Height > 0 {
+			scaleX = max(min(scaleX, fitY), 1)
+		}
+		scaleY = scaleX
+	}
+	if opts.Margin == 0 {
+		margin = quietModules * scaleX
+	}
+
+	symbolW, symbolH := cols*scaleX, rows*scaleY
+	width := max(opts.Width, symbolW+2*margin)
+	height := max(opts.Height, symbolH+2*margin)
+	offX, offY := (width-symbolW)/2, (height-symbolH)/2
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	black := color.Gray{Y: 0}
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			if modules[y*cols+x] == 0 {
+				continue
+			}
+			for py := 0; py < scaleY; py++ {
+				for px := 0; px < scaleX; px++ {
+					img.SetGray(offX+x*scaleX+px, offY+y*scaleY+py, black)
+				}
+			}
+		}
+	}
+	return img
+}
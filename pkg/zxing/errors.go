@@ -0,0 +1,171 @@
+package zxing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 可通过 errors.Is 判断的错误类别。两个后端返回的错误都会归入其中之一，
+// 例如重试逻辑可以据此区分"图片中没有条码"（ErrNotFound）和"后端不可用"
+// （ErrBackendUnavailable、ErrRuntimeClosed）。
+var (
+	// ErrNotFound 图片中未找到条码
+	ErrNotFound = errors.New("zxing: no barcode found")
+
+	// ErrChecksum 找到条码但校验失败
+	ErrChecksum = errors.New("zxing: checksum error")
+
+	// ErrFormat 找到条码但数据格式错误
+	ErrFormat = errors.New("zxing: format error")
+
+	// ErrUnsupportedFormat 不支持的条码格式或功能
+	ErrUnsupportedFormat = errors.New("zxing: unsupported format")
+
+	// ErrInvalidInput 参数或输入数据无效，如空图像、尺寸不匹配、内容不符合格式要求
+	ErrInvalidInput = errors.New("zxing: invalid input")
+
+	// ErrBackendUnavailable 后端不可用，如未启用 CGO 或 WASM 模块加载失败
+	ErrBackendUnavailable = errors.New("zxing: backend unavailable")
+
+	// ErrRuntimeClosed WASM 运行时已关闭或未初始化
+	ErrRuntimeClosed = errors.New("zxing: runtime closed")
+
+	// ErrCanceled 操作被 context 取消或超时
+	ErrCanceled = errors.New("zxing: operation canceled")
+)
+
+// Error 后端操作失败时返回的错误。
+// errors.Is 同时匹配 Kind 和 Err，因此既可以判断错误类别，
+// 也可以判断底层原因（如 context.DeadlineExceeded）。
+type Error struct {
+	// Backend 产生错误的后端
+	Backend Backend
+
+	// Op 失败的操作，如 "decode"、"encode"
+	Op string
+
+	// Kind 错误类别，为上面的 Err* 之一；无法归类时为 nil
+	Kind error
+
+	// Err 底层错误，可能为 nil
+	Err error
+}
+
+// Error 实现 error 接口。有底层错误时输出底层错误信息，否则输出错误类别
+func (e *Error) Error() string {
+	prefix := fmt.Sprintf("zxing %s %s", e.Backend, e.Op)
+	switch {
+	case e.Err != nil:
+		return prefix + ": " + e.Err.Error()
+	case e.Kind != nil:
+		return prefix + ": " + strings.TrimPrefix(e.Kind.Error(), "zxing: ")
+	default:
+		return prefix + " failed"
+	}
+}
+
+// Is 判断 target 是否为该错误的类别
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// Unwrap 返回底层错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Error codes reported by the C wrapper (ZXingErrorCode in include/zxing.h).
+const (
+	codeNone         = 0
+	codeNotFound     = 1
+	codeChecksum     = 2
+	codeFormat       = 3
+	codeUnsupported  = 4
+	codeInvalidInput = 5
+	codeInternal     = 6
+)
+
+// codedError is implemented by backend errors that carry a C wrapper error code,
+// such as nativeError from the CGO binding and wasm.GuestError.
+type codedError interface {
+	error
+	ErrorCode() int
+}
+
+// kindFromCode maps a C wrapper error code to an error kind, or nil when the
+// code does not correspond to one.
+func kindFromCode(code int) error {
+	switch code {
+	case codeNotFound:
+		return ErrNotFound
+	case codeChecksum:
+		return ErrChecksum
+	case codeFormat:
+		return ErrFormat
+	case codeUnsupported:
+		return ErrUnsupportedFormat
+	case codeInvalidInput:
+		return ErrInvalidInput
+	default:
+		return nil
+	}
+}
+
+// nativeError is an error reported by the C wrapper through its ZXingError out-parameter.
+type nativeError struct {
+	code int
+	msg  string
+}
+
+func (e *nativeError) Error() string { return e.msg }
+
+// ErrorCode returns the ZXingErrorCode reported with the error.
+func (e *nativeError) ErrorCode() int { return e.code }
+
+// newError returns an *Error of the given kind wrapping err.
+func newError(backend Backend, op string, kind error, err error) error {
+	return &Error{Backend: backend, Op: op, Kind: kind, Err: err}
+}
+
+// wrapError classifies a backend failure and wraps it as an *Error. Errors that
+// are already an *Error are returned unchanged. Context cancellation takes
+// precedence, since a canceled call may fail with an unrelated backend error.
+func wrapError(ctx context.Context, backend Backend, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var zerr *Error
+	if errors.As(err, &zerr) {
+		return err
+	}
+
+	var kind error
+	var coded codedError
+	switch {
+	case ctx != nil && ctx.Err() != nil,
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		kind = ErrCanceled
+	case errors.As(err, &coded):
+		kind = kindFromCode(coded.ErrorCode())
+	}
+	return newError(backend, op, kind, err)
+}
+
+// symbolError returns the error attached to a Result for a symbol that was
+// located but failed to decode, or nil when code is codeNone.
+func symbolError(backend Backend, code int, msg string) error {
+	if code == codeNone {
+		return nil
+	}
+	kind := kindFromCode(code)
+	if kind == nil {
+		kind = ErrFormat
+	}
+	var err error
+	if msg != "" {
+		err = errors.New(msg)
+	}
+	return newError(backend, "decode", kind, err)
+}
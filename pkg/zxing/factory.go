@@ -36,9 +36,13 @@ func NewCGO(config *Config) (ZXing, error) {
 		config = DefaultConfig()
 	}
 	if !cgoAvailable {
-		return nil, fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows with precompiled static libraries in lib/{linux,windows}-x64/)")
+		return nil, newError(BackendCGO, "init", ErrBackendUnavailable,
+			fmt.Errorf("CGO backend is not available (requires CGO_ENABLED=1 on linux or windows with precompiled static libraries in lib/{linux,windows}-x64/)"))
 	}
-	return &cgoZXing{config: config}, nil
+	if err := CheckABI(); err != nil {
+		return nil, newError(BackendCGO, "init", ErrBackendUnavailable, err)
+	}
+	return newCGOZXing(config), nil
 }
 
 // NewWASM creates a WASM (wazero) backend instance.
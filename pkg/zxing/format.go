@@ -0,0 +1,190 @@
+package zxing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format 条码格式位集合，取值与 include/zxing.h 中的 BarcodeFormat 一致。
+// 多个格式可以按位或组合，例如 FormatQRCode | FormatEAN13。
+type Format int
+
+const (
+	// FormatNone 无格式；在 DecodeOptions.PossibleFormats 中表示识别所有格式
+	FormatNone       Format = 0
+	FormatQRCode     Format = 1
+	FormatAztec      Format = 2
+	FormatCodabar    Format = 4
+	FormatCode39     Format = 8
+	FormatCode93     Format = 16
+	FormatCode128    Format = 32
+	FormatDataMatrix Format = 64
+	FormatEAN8       Format = 128
+	FormatEAN13      Format = 256
+	FormatITF        Format = 512
+	FormatMaxiCode   Format = 1024
+	FormatPDF417     Format = 2048
+	FormatUPCA       Format = 4096
+	FormatUPCE       Format = 8192
+
+	FormatDataBar         Format = 16384
+	FormatDataBarExpanded Format = 32768
+	FormatDataBarLimited  Format = 65536
+	FormatMicroQRCode     Format = 131072
+	FormatRMQRCode        Format = 262144
+	FormatDXFilmEdge      Format = 524288
+
+	// FormatAll 所有格式
+	FormatAll Format = 0xFFFFF
+)
+
+// BarcodeFormat 是 Format 的旧名称。
+//
+// Deprecated: 请使用 Format。
+type BarcodeFormat = Format
+
+// formatNames 每个格式的规范名称，按位值升序排列
+var formatNames = []struct {
+	format Format
+	name   string
+}{
+	{FormatQRCode, "QR_CODE"},
+	{FormatAztec, "AZTEC"},
+	{FormatCodabar, "CODABAR"},
+	{FormatCode39, "CODE_39"},
+	{FormatCode93, "CODE_93"},
+	{FormatCode128, "CODE_128"},
+	{FormatDataMatrix, "DATA_MATRIX"},
+	{FormatEAN8, "EAN_8"},
+	{FormatEAN13, "EAN_13"},
+	{FormatITF, "ITF"},
+	{FormatMaxiCode, "MAXICODE"},
+	{FormatPDF417, "PDF_417"},
+	{FormatUPCA, "UPC_A"},
+	{FormatUPCE, "UPC_E"},
+	{FormatDataBar, "DATABAR"},
+	{FormatDataBarExpanded, "DATABAR_EXPANDED"},
+	{FormatDataBarLimited, "DATABAR_LIMITED"},
+	{FormatMicroQRCode, "MICRO_QR_CODE"},
+	{FormatRMQRCode, "RMQR_CODE"},
+	{FormatDXFilmEdge, "DX_FILM_EDGE"},
+}
+
+// formatAliases 规范名称之外的别名（键为归一化后的名称）
+var formatAliases = map[string]Format{
+	"QR":          FormatQRCode,
+	"MICROQR":     FormatMicroQRCode,
+	"RMQR":        FormatRMQRCode,
+	"RSS14":       FormatDataBar,
+	"DATABAREXP":  FormatDataBarExpanded,
+	"RSSEXPANDED": FormatDataBarExpanded,
+	"DATABARLTD":  FormatDataBarLimited,
+	"NONE":        FormatNone,
+	"ALL":         FormatAll,
+}
+
+// normalizeFormatName 将格式名称转为大写并去掉 '_'、'-' 和空格，
+// 使 "QR_CODE"、"QRCode"、"qr-code" 和 "QR Code" 等写法等价
+func normalizeFormatName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(name)))
+}
+
+// ParseFormat 解析单个格式名称，支持规范名称及常见写法（大小写、下划线、连字符和空格不敏感）
+func ParseFormat(name string) (Format, error) {
+	key := normalizeFormatName(name)
+	for _, f := range formatNames {
+		if normalizeFormatName(f.name) == key {
+			return f.format, nil
+		}
+	}
+	if f, ok := formatAliases[key]; ok {
+		return f, nil
+	}
+	return FormatNone, fmt.Errorf("unknown barcode format %q", name)
+}
+
+// ParseFormats 解析以逗号或 '|' 分隔的格式列表，返回按位或后的结果。
+// 空字符串返回 FormatNone，任一未知名称都会返回错误。
+func ParseFormats(list string) (Format, error) {
+	var formats Format
+	for _, name := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		f, err := ParseFormat(name)
+		if err != nil {
+			return FormatNone, err
+		}
+		formats |= f
+	}
+	return formats, nil
+}
+
+// Has 判断 f 是否包含 other 中的全部格式
+func (f Format) Has(other Format) bool {
+	return f&other == other
+}
+
+// Formats 将位集合拆分为单个格式列表
+func (f Format) Formats() []Format {
+	var list []Format
+	for _, n := range formatNames {
+		if f&n.format != 0 {
+			list = append(list, n.format)
+		}
+	}
+	return list
+}
+
+// String 返回格式的规范名称，组合格式以 '|' 连接
+func (f Format) String() string {
+	switch f {
+	case FormatNone:
+		return "NONE"
+	case FormatAll:
+		return "ALL"
+	}
+
+	var names []string
+	rest := f
+	for _, n := range formatNames {
+		if f&n.format != 0 {
+			names = append(names, n.name)
+			rest &^= n.format
+		}
+	}
+	if rest != 0 {
+		names = append(names, fmt.Sprintf("Format(0x%x)", int(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出规范名称
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受 ParseFormats 支持的写法
+func (f *Format) UnmarshalText(text []byte) error {
+	parsed, err := ParseFormats(string(text))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
+
+// formatFromName 将后端返回的格式名称转换为 Format，无法识别时返回 FormatNone
+func formatFromName(name string) Format {
+	f, err := ParseFormat(name)
+	if err != nil {
+		return FormatNone
+	}
+	return f
+}
@@ -3,7 +3,9 @@ package zxing
 
 import (
 	"context"
+	"fmt"
 	"image"
+	"strings"
 )
 
 // Result 解码结果
@@ -11,11 +13,57 @@ type Result struct {
 	// Text 解码得到的文本内容
 	Text string
 	
-	// Format 条码格式（如 QR_CODE, CODE_128 等）
-	Format string
+	// Format 条码格式（如 FormatQRCode, FormatCode128 等）
+	Format Format
 	
 	// Points 条码在图像中的位置点
 	Points []image.Point
+
+	// SymbologyIdentifier 符号标识符（如 "]Q1"）
+	SymbologyIdentifier string
+
+	// ECLevel 纠错级别（不适用时为空）
+	ECLevel string
+
+	// Version 符号版本（不适用时为空）
+	Version string
+
+	// Orientation 条码方向（角度）
+	Orientation int
+
+	// IsMirrored 条码是否镜像
+	IsMirrored bool
+
+	// IsInverted 条码是否反色
+	IsInverted bool
+
+	// LineCount 检测到的扫描线数量（仅线性条码）
+	LineCount int
+
+	// ContentType 内容类型
+	ContentType ContentType
+
+	// HasECI 是否包含 ECI 标记
+	HasECI bool
+
+	// Bytes 条码中的原始数据（未经字符集转换，可包含 0 字节）
+	Bytes []byte
+
+	// ECISegments 按 ECI 划分的数据段
+	ECISegments []ECISegment
+
+	// StructuredAppend 结构化追加序列信息（不属于序列时为 nil）
+	StructuredAppend *StructuredAppend
+
+	// AddOn EAN/UPC 条码的 EAN-2/EAN-5 附加码（如期刊期号、图书价格），
+	// 仅在 DecodeOptions.EANAddOn 不为 EANAddOnIgnore 时读取；无附加码时为空。
+	// Text 中附加码以空格接在主码之后
+	AddOn string
+
+	// Error 条码已定位但解码失败时的错误（仅在 DecodeOptions.ReturnErrors 开启时出现），
+	// 可通过 errors.Is 判断为 ErrChecksum、ErrFormat 或 ErrUnsupportedFormat。
+	// 此时 Points 和 Format 有效，Text 可能为空或不完整；解码成功时为 nil
+	Error error
 	
 	// Metadata 额外的元数据信息
 	Metadata map[string]interface{}
@@ -30,25 +78,231 @@ type EncodeOptions struct {
 	Height int
 	
 	// Format 条码格式
-	Format string
+	Format Format
 	
-	// ErrorCorrectionLevel 错误纠正级别（适用于二维码）
+	// ErrorCorrectionLevel 错误纠正级别（QR Code 为 L/M/Q/H，空字符串使用默认值）
 	ErrorCorrectionLevel string
 	
-	// Margin 边距大小
+	// Margin 静区大小（像素）；0 使用该条码类型的默认静区，负数表示不留静区
 	Margin int
 }
 
-// DecodeOptions 解码选项
+// DecodeOptions 解码选项。
+// 布尔选项的零值表示关闭；TryRotate 和 TryDownscale 为 nil 时开启，
+// 因此 &DecodeOptions{TryHarder: true} 与旧版本一样会尝试旋转和缩小。
+// 传入 nil 等价于 DefaultDecodeOptions()。
 type DecodeOptions struct {
 	// TryHarder 是否尝试更努力地解码
 	TryHarder bool
+
+	// TryRotate 是否尝试旋转 90°/180°/270° 后解码；nil 时开启，关闭需传入 new(bool)
+	TryRotate *bool
+
+	// TryInvert 是否尝试反色解码（深色背景上的浅色条码）
+	TryInvert bool
+
+	// TryDownscale 是否尝试缩小大图后解码；nil 时开启，关闭需传入 new(bool)
+	TryDownscale *bool
+
+	// Binarizer 二值化算法，默认 BinarizerLocalAverage
+	Binarizer Binarizer
+
+	// DownscaleThreshold 开启 TryDownscale 时，图像短边超过该值才缩小（0 使用默认值 500）
+	DownscaleThreshold int
+
+	// DownscaleFactor 每次缩小的倍数，取值 2、3 或 4（0 使用默认值 3）
+	DownscaleFactor int
+
+	// IsPure 图像仅包含一个对齐、无畸变的条码（如程序生成的图像），可显著加快识别
+	IsPure bool
+
+	// MinLineCount 线性条码需要一致的最少扫描线数（0 使用默认值 2），增大可减少误识别
+	MinLineCount int
 	
-	// PossibleFormats 可能的格式列表
-	PossibleFormats []string
+	// PossibleFormats 可能的格式，多个格式按位或组合（FormatNone 表示所有格式）
+	PossibleFormats Format
 	
-	// CharacterSet 字符集
+	// CharacterSet 条码未声明 ECI 时使用的字符集，如 "Shift_JIS"、"GB18030"、"ISO-8859-1"
+	// （大小写、连字符和下划线不敏感）；为空时自动检测。不支持的名称返回 ErrInvalidInput
 	CharacterSet string
+
+	// MaxSymbols 多码识别时查找和返回的最大条码数量（0 表示不限制，最大 255）
+	MaxSymbols int
+
+	// ReturnErrors 同时返回已定位但解码失败的条码（如污损、校验失败），
+	// 这些结果的 Result.Error 非 nil，可用于提示"此处有条码但已损坏"
+	ReturnErrors bool
+
+	// TextMode Result.Text 的输出形式（零值为 TextModeHRI），不影响 Result.Bytes
+	TextMode TextMode
+
+	// EANAddOn EAN/UPC 附加码的处理方式（零值为 EANAddOnIgnore），读取结果见 Result.AddOn
+	EANAddOn EANAddOn
+
+	// Symbology Code 39、ITF 和 Codabar 的专用选项；nil 等价于 DefaultSymbologyOptions()
+	Symbology *SymbologyOptions
+
+	// Region 只在该矩形区域内识别（零值表示整幅图像），不拷贝区域外的像素。
+	// DecodeImage 使用图像坐标，DecodeBytes 以缓冲区左上角为原点；超出图像的部分被裁掉，
+	// 与图像不相交时返回 ErrInvalidInput。Result.Points 仍为整幅图像坐标
+	Region image.Rectangle
+
+	// Stride DecodeBytes / DecodeMultiBytes 中相邻两行起始位置相差的字节数，
+	// 0 表示紧密排列（width*4）
+	Stride int
+}
+
+// SymbologyOptions 特定条码类型的识别选项。
+// 不满足校验位或长度要求的条码视为未找到
+type SymbologyOptions struct {
+	// Code39ExtendedMode 按 Full ASCII（扩展）模式解释 Code 39，如 "+A" 解码为 "a"；nil 时开启，关闭需传入 new(bool)
+	Code39ExtendedMode *bool
+
+	// Code39CheckDigit 只接受带有正确 mod 43 校验位的 Code 39（校验位保留在 Text 中）
+	Code39CheckDigit bool
+
+	// ITFCheckDigit 只接受带有正确 mod 10 校验位的 ITF（如 ITF-14）
+	ITFCheckDigit bool
+
+	// ITFMinLength ITF 最少位数（0 表示不限制）
+	ITFMinLength int
+
+	// ITFMaxLength ITF 最多位数（0 表示不限制），只识别 ITF-14 时可将两者都设为 14
+	ITFMaxLength int
+
+	// CodabarStartStop 在 Text 中保留 Codabar 起止符（A、B、C、D）；nil 时开启，关闭需传入 new(bool)
+	CodabarStartStop *bool
+}
+
+// DefaultSymbologyOptions 返回与 zxing-cpp 默认行为一致的选项：
+// 开启 Code 39 Full ASCII 模式、保留 Codabar 起止符，不校验可选校验位，不限制 ITF 长度
+func DefaultSymbologyOptions() *SymbologyOptions {
+	return &SymbologyOptions{}
+}
+
+// Binarizer 二值化算法，决定灰度图转为黑白图时的阈值
+type Binarizer int
+
+const (
+	// BinarizerLocalAverage 局部均值（默认），适合光照不均的相机图像
+	BinarizerLocalAverage Binarizer = 0
+
+	// BinarizerGlobalHistogram 全局直方图
+	BinarizerGlobalHistogram Binarizer = 1
+
+	// BinarizerFixedThreshold 固定阈值 127，适合程序生成的清晰图像
+	BinarizerFixedThreshold Binarizer = 2
+
+	// BinarizerBoolCast 非零像素即为浅色，最快
+	BinarizerBoolCast Binarizer = 3
+)
+
+// binarizerNames 二值化算法名称
+var binarizerNames = []string{"LocalAverage", "GlobalHistogram", "FixedThreshold", "BoolCast"}
+
+// String 返回二值化算法名称
+func (b Binarizer) String() string {
+	if b >= 0 && int(b) < len(binarizerNames) {
+		return binarizerNames[b]
+	}
+	return fmt.Sprintf("Binarizer(%d)", int(b))
+}
+
+// ParseBinarizer 解析二值化算法名称（大小写、下划线和连字符不敏感）
+func ParseBinarizer(name string) (Binarizer, error) {
+	key := normalizeFormatName(name)
+	for i, n := range binarizerNames {
+		if strings.ToUpper(n) == key {
+			return Binarizer(i), nil
+		}
+	}
+	return BinarizerLocalAverage, fmt.Errorf("unknown binarizer %q", name)
+}
+
+// TextMode 解码结果文本的输出形式
+type TextMode int
+
+const (
+	// TextModeHRI 人类可读形式（默认），如 GS1 条码输出 "(01)09501101530003"
+	TextModeHRI TextMode = 0
+
+	// TextModePlain 按 ECI 或推测的字符集转码后的原始内容，GS1 分隔符保留为 <GS> 控制字符
+	TextModePlain TextMode = 1
+
+	// TextModeECI 遵循 ECI 协议的内容，每个字符集段转码为 Unicode 并保留 ECI 标记
+	TextModeECI TextMode = 2
+
+	// TextModeHex 原始字节的十六进制表示
+	TextModeHex TextMode = 3
+
+	// TextModeEscaped 将不可打印字符转义为 "<GS>"、"<U+1F>" 等形式，便于记录日志
+	TextModeEscaped TextMode = 4
+)
+
+// textModeNames 文本模式名称
+var textModeNames = []string{"HRI", "Plain", "ECI", "Hex", "Escaped"}
+
+// String 返回文本模式名称
+func (m TextMode) String() string {
+	if m >= 0 && int(m) < len(textModeNames) {
+		return textModeNames[m]
+	}
+	return fmt.Sprintf("TextMode(%d)", int(m))
+}
+
+// ParseTextMode 解析文本模式名称（大小写、下划线和连字符不敏感）
+func ParseTextMode(name string) (TextMode, error) {
+	key := normalizeFormatName(name)
+	for i, n := range textModeNames {
+		if strings.ToUpper(n) == key {
+			return TextMode(i), nil
+		}
+	}
+	return TextModeHRI, fmt.Errorf("unknown text mode %q", name)
+}
+
+// EANAddOn EAN/UPC 附加码（EAN-2/EAN-5）的处理方式
+type EANAddOn int
+
+const (
+	// EANAddOnIgnore 忽略附加码（默认）
+	EANAddOnIgnore EANAddOn = 0
+
+	// EANAddOnRead 存在附加码时一并读取
+	EANAddOnRead EANAddOn = 1
+
+	// EANAddOnRequire 只返回带附加码的 EAN/UPC 条码，适合期刊、图书等必带附加码的场景
+	EANAddOnRequire EANAddOn = 2
+)
+
+// eanAddOnNames 附加码处理方式名称
+var eanAddOnNames = []string{"Ignore", "Read", "Require"}
+
+// String 返回附加码处理方式名称
+func (a EANAddOn) String() string {
+	if a >= 0 && int(a) < len(eanAddOnNames) {
+		return eanAddOnNames[a]
+	}
+	return fmt.Sprintf("EANAddOn(%d)", int(a))
+}
+
+// ParseEANAddOn 解析附加码处理方式名称（大小写不敏感）
+func ParseEANAddOn(name string) (EANAddOn, error) {
+	key := normalizeFormatName(name)
+	for i, n := range eanAddOnNames {
+		if strings.ToUpper(n) == key {
+			return EANAddOn(i), nil
+		}
+	}
+	return EANAddOnIgnore, fmt.Errorf("unknown EAN add-on mode %q", name)
+}
+
+// DefaultDecodeOptions 返回默认解码选项：开启 TryHarder、TryRotate 和 TryDownscale，
+// 关闭 TryInvert，识别所有格式
+func DefaultDecodeOptions() *DecodeOptions {
+	return &DecodeOptions{
+		TryHarder: true,
+	}
 }
 
 // Decoder 解码器接口
@@ -58,6 +312,12 @@ type Decoder interface {
 	
 	// DecodeBytes 解码字节数据
 	DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error)
+	
+	// DecodeMultiImage 解码图像中的所有条码
+	DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error)
+	
+	// DecodeMultiBytes 解码字节数据中的所有条码
+	DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error)
 }
 
 // Encoder 编码器接口
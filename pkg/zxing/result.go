@@ -0,0 +1,161 @@
+package zxing
+
+// ContentType 条码内容类型，取值与 zxing-cpp 的 ContentType 一致
+type ContentType int
+
+const (
+	// ContentTypeText 纯文本内容
+	ContentTypeText ContentType = iota
+
+	// ContentTypeBinary 二进制内容
+	ContentTypeBinary
+
+	// ContentTypeMixed 文本与二进制混合内容
+	ContentTypeMixed
+
+	// ContentTypeGS1 GS1 数据
+	ContentTypeGS1
+
+	// ContentTypeISO15434 ISO 15434 数据
+	ContentTypeISO15434
+
+	// ContentTypeUnknownECI 包含未知 ECI 的内容
+	ContentTypeUnknownECI
+)
+
+// String 返回内容类型名称
+func (c ContentType) String() string {
+	switch c {
+	case ContentTypeText:
+		return "Text"
+	case ContentTypeBinary:
+		return "Binary"
+	case ContentTypeMixed:
+		return "Mixed"
+	case ContentTypeGS1:
+		return "GS1"
+	case ContentTypeISO15434:
+		return "ISO15434"
+	case ContentTypeUnknownECI:
+		return "UnknownECI"
+	default:
+		return "Unknown"
+	}
+}
+
+// ECISegment 一段使用相同 ECI 编码的原始数据
+type ECISegment struct {
+	// ECI ECI 编号（-1 表示条码中未声明 ECI）
+	ECI int
+
+	// Charset ECI 对应的字符集名称（未知时为空）
+	Charset string
+
+	// Data 该段的原始字节
+	Data []byte
+}
+
+// eciCharsets ECI 编号到字符集名称的映射（ISO/IEC 15424 / AIM ECI）
+var eciCharsets = map[int]string{
+	0:   "Cp437",
+	1:   "ISO-8859-1",
+	2:   "Cp437",
+	3:   "ISO-8859-1",
+	4:   "ISO-8859-2",
+	5:   "ISO-8859-3",
+	6:   "ISO-8859-4",
+	7:   "ISO-8859-5",
+	8:   "ISO-8859-6",
+	9:   "ISO-8859-7",
+	10:  "ISO-8859-8",
+	11:  "ISO-8859-9",
+	12:  "ISO-8859-10",
+	13:  "ISO-8859-11",
+	15:  "ISO-8859-13",
+	16:  "ISO-8859-14",
+	17:  "ISO-8859-15",
+	18:  "ISO-8859-16",
+	20:  "Shift_JIS",
+	21:  "windows-1250",
+	22:  "windows-1251",
+	23:  "windows-1252",
+	24:  "windows-1256",
+	25:  "UTF-16BE",
+	26:  "UTF-8",
+	27:  "US-ASCII",
+	28:  "Big5",
+	29:  "GB2312",
+	30:  "EUC-KR",
+	31:  "GBK",
+	32:  "GB18030",
+	33:  "UTF-16LE",
+	34:  "UTF-32BE",
+	35:  "UTF-32LE",
+	170: "ISO646-Inv",
+	899: "Binary",
+}
+
+// ECICharset 返回 ECI 编号对应的字符集名称，未知时返回空字符串
+func ECICharset(eci int) string {
+	return eciCharsets[eci]
+}
+
+// parseECISegments 解析 zxing-cpp bytesECI() 的输出。
+// 数据以 prefixLen 字节的符号标识符开头，ECI 标记形如 `\dddddd`，
+// 数据中的反斜杠被转义为 `\\`。
+func parseECISegments(bytesECI []byte, prefixLen int) []ECISegment {
+	if len(bytesECI) < prefixLen {
+		return nil
+	}
+	data := bytesECI[prefixLen:]
+
+	var segments []ECISegment
+	current := ECISegment{ECI: -1}
+	started := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == '\\' {
+			if i+1 < len(data) && data[i+1] == '\\' {
+				current.Data = append(current.Data, '\\')
+				i++
+				started = true
+				continue
+			}
+			if eci, ok := parseECIDesignator(data[i+1:]); ok {
+				if started {
+					segments = append(segments, current)
+				}
+				current = ECISegment{ECI: eci, Charset: ECICharset(eci)}
+				started = true
+				i += 6
+				continue
+			}
+		}
+		current.Data = append(current.Data, c)
+		started = true
+	}
+	if started {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+// parseECIDesignator 解析 `\` 之后的 6 位 ECI 编号
+func parseECIDesignator(b []byte) (int, bool) {
+	if len(b) < 6 {
+		return 0, false
+	}
+	eci := 0
+	for _, d := range b[:6] {
+		if d < '0' || d > '9' {
+			return 0, false
+		}
+		eci = eci*10 + int(d-'0')
+	}
+	return eci, true
+}
+
+// IsValid 判断条码是否解码成功（Error 为 nil）
+func (r *Result) IsValid() bool {
+	return r.Error == nil
+}
@@ -0,0 +1,135 @@
+package zxing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StructuredAppend 结构化追加（多个条码拼接为一条消息）的序列信息
+type StructuredAppend struct {
+	// Index 当前条码在序列中的索引（从 0 开始）
+	Index int
+
+	// Count 序列中的条码总数（0 表示未知，如 PDF417 未给出 Segment Count）
+	Count int
+
+	// ID 序列标识，QR Code 为奇偶校验值，PDF417 和 Data Matrix 为 fileId
+	ID string
+}
+
+// newStructuredAppend 根据后端返回的序列字段创建 StructuredAppend，不属于序列时返回 nil
+func newStructuredAppend(size, index int, id string) *StructuredAppend {
+	if size < 0 || index < 0 {
+		return nil
+	}
+	return &StructuredAppend{Index: index, Count: size, ID: id}
+}
+
+// StructuredAppendMessage 由同一序列的多个条码重组得到的消息
+type StructuredAppendMessage struct {
+	// Format 条码格式
+	Format Format
+
+	// ID 序列标识
+	ID string
+
+	// Count 序列中的条码总数（0 表示未知）
+	Count int
+
+	// Parts 按索引排序且去重后的条码结果
+	Parts []*Result
+
+	// Missing 缺失的条码索引
+	Missing []int
+
+	// Complete 是否已收集到序列中的全部条码
+	Complete bool
+
+	// Bytes 按顺序拼接的原始数据（Complete 为 false 时仅包含已收集的部分）
+	Bytes []byte
+
+	// Text 按顺序拼接的文本
+	Text string
+}
+
+// ReassembleStructuredAppend 将属于结构化追加序列的结果按格式和序列标识分组并重组。
+// results 可以来自一张或多张图片，重复的条码（相同索引）只保留第一个。
+// 不属于任何序列或解码失败（Error 非 nil）的结果会被忽略。返回的消息按首次出现的顺序排列。
+func ReassembleStructuredAppend(results []*Result) []*StructuredAppendMessage {
+	type groupKey struct {
+		format Format
+		id     string
+	}
+
+	var order []groupKey
+	groups := make(map[groupKey]map[int]*Result)
+	counts := make(map[groupKey]int)
+	for _, result := range results {
+		if result == nil || result.StructuredAppend == nil || result.Error != nil {
+			continue
+		}
+		sa := result.StructuredAppend
+		key := groupKey{format: result.Format, id: sa.ID}
+		parts, ok := groups[key]
+		if !ok {
+			parts = make(map[int]*Result)
+			groups[key] = parts
+			order = append(order, key)
+		}
+		if _, dup := parts[sa.Index]; !dup {
+			parts[sa.Index] = result
+		}
+		if sa.Count > counts[key] {
+			counts[key] = sa.Count
+		}
+	}
+
+	messages := make([]*StructuredAppendMessage, 0, len(order))
+	for _, key := range order {
+		parts := groups[key]
+		msg := &StructuredAppendMessage{
+			Format: key.format,
+			ID:     key.id,
+			Count:  counts[key],
+		}
+
+		indexes := make([]int, 0, len(parts))
+		for index := range parts {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		// 总数未知时，以已见到的最大索引为界计算缺失部分
+		expected := msg.Count
+		if expected <= 0 {
+			expected = indexes[len(indexes)-1] + 1
+		}
+		for i := 0; i < expected; i++ {
+			if _, ok := parts[i]; !ok {
+				msg.Missing = append(msg.Missing, i)
+			}
+		}
+		msg.Complete = msg.Count > 0 && len(msg.Missing) == 0
+
+		for _, index := range indexes {
+			part := parts[index]
+			msg.Parts = append(msg.Parts, part)
+			msg.Bytes = append(msg.Bytes, part.Bytes...)
+			msg.Text += part.Text
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// String 返回序列信息的简要描述，如 "2/4 (id 17)"
+func (s *StructuredAppend) String() string {
+	count := "?"
+	if s.Count > 0 {
+		count = fmt.Sprint(s.Count)
+	}
+	if s.ID == "" {
+		return fmt.Sprintf("%d/%s", s.Index+1, count)
+	}
+	return fmt.Sprintf("%d/%s (id %s)", s.Index+1, count, s.ID)
+}
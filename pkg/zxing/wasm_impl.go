@@ -6,9 +6,9 @@ package zxing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/color"
 	"sync"
 
 	"github.com/chennqqi/zxing/pkg/wasm"
@@ -22,7 +22,7 @@ type wasmZXing struct {
 }
 
 // ensureRuntime lazily initializes the WASM runtime.
-// It re-initializes if the previous runtime was closed (e.g. by context cancellation).
+// It re-initializes if the previous runtime was closed.
 func (w *wasmZXing) ensureRuntime(ctx context.Context) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -37,194 +37,313 @@ func (w *wasmZXing) ensureRuntime(ctx context.Context) error {
 		w.runtime = nil
 	}
 
-	w.runtime = wasm.NewRuntime()
+	w.runtime = wasm.NewRuntimeWithConcurrency(concurrency(w.config))
+	w.runtime.SetCompilationCacheDir(w.config.CompilationCacheDir)
 	if err := w.runtime.Initialize(ctx, w.config.WASMPath); err != nil {
 		w.runtime = nil
-		return fmt.Errorf("failed to initialize WASM runtime: %w", err)
+		err = fmt.Errorf("failed to initialize WASM runtime: %w", err)
+		if ctx.Err() != nil {
+			return newError(BackendWASM, "init", ErrCanceled, err)
+		}
+		return newError(BackendWASM, "init", ErrBackendUnavailable, err)
 	}
 	return nil
 }
 
+// loadedRuntime returns the runtime read under w.mu, so a concurrent Close or
+// re-initialization cannot race the caller, or ErrBackendUnavailable when there
+// is none.
+func (w *wasmZXing) loadedRuntime(op string) (*wasm.Runtime, error) {
+	w.mu.Lock()
+	rt := w.runtime
+	w.mu.Unlock()
+	if rt == nil {
+		return nil, newError(BackendWASM, op, ErrBackendUnavailable, errors.New("WASM runtime is not initialized"))
+	}
+	return rt, nil
+}
+
 // DecodeImage decodes an image using the WASM backend.
 func (w *wasmZXing) DecodeImage(ctx context.Context, img image.Image, opts *DecodeOptions) (*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
 	if err := w.ensureRuntime(ctx); err != nil {
 		return nil, err
 	}
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Convert image to RGBA byte data
-	data := make([]byte, width*height*4)
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			idx := (y*width + x) * 4
-			data[idx] = uint8(r >> 8)
-			data[idx+1] = uint8(g >> 8)
-			data[idx+2] = uint8(b >> 8)
-			data[idx+3] = uint8(a >> 8)
-		}
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	result, err := w.decodePixels(ctx, data, width, height, 1, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return w.DecodeBytes(ctx, data, width, height, opts)
+	offsetPoints([]*Result{result}, img.Bounds().Min)
+	return result, nil
 }
 
 // DecodeBytes decodes raw RGBA byte data using the WASM backend.
 func (w *wasmZXing) DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
 	if err := w.ensureRuntime(ctx); err != nil {
 		return nil, err
 	}
 
-	if len(data) == 0 {
-		return nil, fmt.Errorf("empty image data")
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	result, err := w.decodePixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints([]*Result{result}, rect.Min)
+	return result, nil
+}
+
+// decodePixels decodes validated pixel data with the given channel count.
+func (w *wasmZXing) decodePixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) (*Result, error) {
+	if err := validateDecodeOptions(opts); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
 	}
 
 	runtimeOpts := mapDecodeOptions(opts)
 
-	result, err := w.runtime.DecodeImage(ctx, data, width, height, 4, runtimeOpts)
+	rt, err := w.loadedRuntime("decode")
+	if err != nil {
+		return nil, err
+	}
+	result, err := rt.DecodeImage(ctx, data, width, height, channels, runtimeOpts)
 	if err != nil {
-		return nil, fmt.Errorf("WASM decode failed: %w", err)
+		return nil, wrapRuntimeError(ctx, "decode", err)
 	}
 
 	if !result.Success {
-		return nil, fmt.Errorf("decode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+		return nil, newError(BackendWASM, "decode", kindFromCode(result.ErrorCode),
+			fmt.Errorf("decode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode))
+	}
+
+	return convertWASMResult(result), nil
+}
+
+// DecodeMultiImage decodes every barcode in an image using the WASM backend.
+func (w *wasmZXing) DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if err := w.ensureRuntime(ctx); err != nil {
+		return nil, err
+	}
+
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	results, err := w.decodeMultiPixels(ctx, data, width, height, 1, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints(results, img.Bounds().Min)
+	return results, nil
+}
+
+// DecodeMultiBytes decodes every barcode in raw RGBA byte data using the WASM backend.
+func (w *wasmZXing) DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if err := w.ensureRuntime(ctx); err != nil {
+		return nil, err
+	}
+
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
 	}
 
+	results, err := w.decodeMultiPixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints(results, rect.Min)
+	return results, nil
+}
+
+// decodeMultiPixels decodes every barcode in validated pixel data with the given channel count.
+func (w *wasmZXing) decodeMultiPixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) ([]*Result, error) {
+	if err := validateDecodeOptions(opts); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	runtimeOpts := mapDecodeOptions(opts)
+
+	rt, err := w.loadedRuntime("decode")
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := rt.DecodeMultiImage(ctx, data, width, height, channels, runtimeOpts)
+	if err != nil {
+		return nil, wrapRuntimeError(ctx, "decode", err)
+	}
+
+	results := make([]*Result, 0, len(decoded))
+	for _, result := range decoded {
+		if result.Success {
+			results = append(results, convertWASMResult(result))
+		}
+	}
+
+	return limitResults(results, opts), nil
+}
+
+// wrapRuntimeError classifies an error returned by the wazero runtime.
+func wrapRuntimeError(ctx context.Context, op string, err error) error {
+	if errors.Is(err, wasm.ErrClosed) {
+		return newError(BackendWASM, op, ErrRuntimeClosed, err)
+	}
+	if errors.Is(err, wasm.ErrUnsupported) {
+		return newError(BackendWASM, op, ErrUnsupportedFormat, err)
+	}
+	return wrapError(ctx, BackendWASM, op, err)
+}
+
+// convertWASMResult converts a wasm.DecodeResult to the public Result type.
+func convertWASMResult(result *wasm.DecodeResult) *Result {
 	return &Result{
 		Text:   result.Text,
-		Format: result.Format,
-		Points: []image.Point{},
+		Format: formatFromName(result.Format),
+		Points: append([]image.Point(nil), result.Points...),
+
+		SymbologyIdentifier: result.SymbologyIdentifier,
+		ECLevel:             result.ECLevel,
+		Version:             result.Version,
+		Orientation:         result.Orientation,
+		IsMirrored:          result.IsMirrored,
+		IsInverted:          result.IsInverted,
+		LineCount:           result.LineCount,
+		ContentType:         ContentType(result.ContentType),
+		HasECI:              result.HasECI,
+		Bytes:               result.Bytes,
+		ECISegments:         parseECISegments(result.BytesECI, len(result.SymbologyIdentifier)),
+		StructuredAppend:    newStructuredAppend(result.SequenceSize, result.SequenceIndex, result.SequenceID),
+		Error:               symbolError(BackendWASM, result.ErrorCode, result.ErrorMessage),
+		AddOn:               result.AddOn,
 		Metadata: map[string]interface{}{
 			"backend": "wasm",
 		},
-	}, nil
+	}
 }
 
 // mapDecodeOptions converts public DecodeOptions to wasm.DecodeOptions.
 func mapDecodeOptions(opts *DecodeOptions) *wasm.DecodeOptions {
 	if opts == nil {
-		return nil
+		opts = DefaultDecodeOptions()
 	}
-
-	var formatFlags int
-	for _, f := range opts.PossibleFormats {
-		switch f {
-		case "QR_CODE":
-			formatFlags |= 1
-		case "AZTEC":
-			formatFlags |= 2
-		case "CODABAR":
-			formatFlags |= 4
-		case "CODE_39":
-			formatFlags |= 8
-		case "CODE_93":
-			formatFlags |= 16
-		case "CODE_128":
-			formatFlags |= 32
-		case "DATA_MATRIX":
-			formatFlags |= 64
-		case "EAN_8":
-			formatFlags |= 128
-		case "EAN_13":
-			formatFlags |= 256
-		case "ITF":
-			formatFlags |= 512
-		case "MAXICODE":
-			formatFlags |= 1024
-		case "PDF_417":
-			formatFlags |= 2048
-		case "UPC_A":
-			formatFlags |= 4096
-		case "UPC_E":
-			formatFlags |= 8192
+	// The name was checked by validateDecodeOptions
+	charset, _ := canonicalCharacterSet(opts.CharacterSet)
+
+	var symbology *wasm.SymbologyOptions
+	if sym := opts.Symbology; sym != nil {
+		symbology = &wasm.SymbologyOptions{
+			Code39Extended:   code39ExtendedMode(sym),
+			Code39CheckDigit: sym.Code39CheckDigit,
+			ITFCheckDigit:    sym.ITFCheckDigit,
+			ITFMinLength:     sym.ITFMinLength,
+			ITFMaxLength:     sym.ITFMaxLength,
+			CodabarStartStop: codabarStartStop(sym),
 		}
 	}
-	if formatFlags == 0 {
-		formatFlags = 0xFFFF // FORMAT_ALL
-	}
 
 	return &wasm.DecodeOptions{
-		Formats:      formatFlags,
+		Formats:      int(possibleFormats(opts)),
 		TryHarder:    opts.TryHarder,
-		TryRotate:    true,
-		TryInvert:    false,
-		TryDownscale: true,
+		TryRotate:    tryRotate(opts),
+		TryInvert:    opts.TryInvert,
+		TryDownscale: tryDownscale(opts),
+
+		Binarizer:          int(opts.Binarizer),
+		DownscaleThreshold: opts.DownscaleThreshold,
+		DownscaleFactor:    opts.DownscaleFactor,
+		IsPure:             opts.IsPure,
+		MinLineCount:       opts.MinLineCount,
+		MaxSymbols:         opts.MaxSymbols,
+		ReturnErrors:       opts.ReturnErrors,
+		TextMode:           int(opts.TextMode),
+		CharacterSet:       charset,
+		EANAddOn:           int(opts.EANAddOn),
+		Symbology:          symbology,
 	}
 }
 
 // EncodeText encodes text to a barcode image using the WASM backend.
 func (w *wasmZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
 	if err := w.ensureRuntime(ctx); err != nil {
 		return nil, err
 	}
 
 	if len(text) == 0 {
-		return nil, fmt.Errorf("empty text")
+		return nil, newError(BackendWASM, "encode", ErrInvalidInput, fmt.Errorf("empty text"))
 	}
 
 	if opts == nil {
-		opts = &EncodeOptions{
-			Width:  256,
-			Height: 256,
-			Format: "QR_CODE",
-		}
+		opts = defaultEncodeOptions()
 	}
 
-	result, err := w.runtime.EncodeText(text, opts.Width, opts.Height)
+	format, ecLevel, err := encodeParams(opts)
 	if err != nil {
-		return nil, fmt.Errorf("WASM encode failed: %w", err)
+		return nil, newError(BackendWASM, "encode", ErrInvalidInput, err)
 	}
 
-	if !result.Success {
-		return nil, fmt.Errorf("encode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+	rt, err := w.loadedRuntime("encode")
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := rt.EncodeText(ctx, text, &wasm.EncodeOptions{Format: int(format), ECLevel: ecLevel})
+	if err != nil {
+		return nil, wrapRuntimeError(ctx, "encode", err)
 	}
 
-	// Convert byte data to image
-	img := image.NewGray(image.Rect(0, 0, result.Width, result.Height))
-	for i, val := range result.Data {
-		if i >= len(result.Data) {
-			break
-		}
-		x := i % result.Width
-		y := i / result.Width
-		if x < result.Width && y < result.Height {
-			img.SetGray(x, y, color.Gray{Y: val})
-		}
+	if !symbol.Success {
+		return nil, newError(BackendWASM, "encode", kindFromCode(symbol.ErrorCode),
+			fmt.Errorf("encode failed: %s (code: %d)", symbol.ErrorMessage, symbol.ErrorCode))
 	}
 
-	return img, nil
+	return renderSymbol(symbol.Data, symbol.Width, symbol.Height, format, opts), nil
 }
 
 // EncodeToBytes encodes text to raw byte data using the WASM backend.
 func (w *wasmZXing) EncodeToBytes(ctx context.Context, text string, opts *EncodeOptions) ([]byte, int, int, error) {
-	if err := w.ensureRuntime(ctx); err != nil {
-		return nil, 0, 0, err
-	}
-
-	if len(text) == 0 {
-		return nil, 0, 0, fmt.Errorf("empty text")
-	}
-
-	if opts == nil {
-		opts = &EncodeOptions{
-			Width:  256,
-			Height: 256,
-			Format: "QR_CODE",
-		}
-	}
-
-	result, err := w.runtime.EncodeText(text, opts.Width, opts.Height)
+	img, err := w.EncodeText(ctx, text, opts)
 	if err != nil {
-		return nil, 0, 0, fmt.Errorf("WASM encode failed: %w", err)
-	}
-
-	if !result.Success {
-		return nil, 0, 0, fmt.Errorf("encode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+		return nil, 0, 0, err
 	}
 
-	return result.Data, result.Width, result.Height, nil
+	data, width, height := imageToRGBA(img)
+	return data, width, height, nil
 }
 
 // Close releases WASM runtime resources.
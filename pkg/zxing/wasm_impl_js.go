@@ -5,100 +5,280 @@ package zxing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"sync"
 
 	"github.com/chennqqi/zxing/pkg/wasm"
 )
 
 // wasmZXing WASM 实现
 type wasmZXing struct {
+	mu      sync.Mutex
 	config  *Config
 	runtime *wasm.Runtime
 }
 
 // DecodeImage 解码图像
 func (w *wasmZXing) DecodeImage(ctx context.Context, img image.Image, opts *DecodeOptions) (*Result, error) {
-	if !w.runtime.IsReady() {
-		return nil, fmt.Errorf("WASM runtime not ready")
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if _, err := w.ready(ctx, "decode"); err != nil {
+		return nil, err
 	}
 
-	// 转换图像为字节数据
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
 
-	data := make([]byte, width*height*4) // RGBA
+	// 转换图像为单通道亮度数据
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			idx := (y*width + x) * 4
-			data[idx] = uint8(r >> 8)
-			data[idx+1] = uint8(g >> 8)
-			data[idx+2] = uint8(b >> 8)
-			data[idx+3] = uint8(a >> 8)
-		}
+	result, err := w.decodePixels(ctx, data, width, height, 1, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return w.DecodeBytes(ctx, data, width, height, opts)
+	offsetPoints([]*Result{result}, img.Bounds().Min)
+	return result, nil
 }
 
 // DecodeBytes 解码字节数据
 func (w *wasmZXing) DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	if !w.runtime.IsReady() {
-		return nil, fmt.Errorf("WASM runtime not ready")
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if _, err := w.ready(ctx, "decode"); err != nil {
+		return nil, err
+	}
+
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	result, err := w.decodePixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(data) == 0 {
-		return nil, fmt.Errorf("empty image data")
+	offsetPoints([]*Result{result}, rect.Min)
+	return result, nil
+}
+
+// decodePixels 解码指定通道数的像素数据
+func (w *wasmZXing) decodePixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) (*Result, error) {
+	if err := checkDecodeOptions(opts); err != nil {
+		return nil, err
+	}
+
+	// JS 调用同步执行，无法中途取消，只能在调用前检查 ctx
+	rt, err := w.ready(ctx, "decode")
+	if err != nil {
+		return nil, err
 	}
 
 	// 调用 WASM 解码函数
-	result, err := w.runtime.DecodeImage(data, width, height, 4)
+	result, err := rt.DecodeImage(data, width, height, channels)
 	if err != nil {
-		return nil, fmt.Errorf("WASM decode failed: %w", err)
+		return nil, wrapRuntimeError(ctx, "decode", err)
 	}
 
 	if !result.Success {
-		return nil, fmt.Errorf("decode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+		return nil, newError(BackendWASM, "decode", kindFromCode(result.ErrorCode),
+			fmt.Errorf("decode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode))
 	}
 
-	return &Result{
-		Text:     result.Text,
-		Format:   result.Format,
-		Points:   []image.Point{}, // TODO: 从 WASM 结果中提取位置信息
-		Metadata: make(map[string]interface{}),
-	}, nil
+	return convertJSResult(result), nil
 }
 
-// EncodeText 编码文本为条码图像
-func (w *wasmZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
-	if !w.runtime.IsReady() {
-		return nil, fmt.Errorf("WASM runtime not ready")
+// DecodeMultiImage 解码图像中的所有条码
+func (w *wasmZXing) DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if _, err := w.ready(ctx, "decode"); err != nil {
+		return nil, err
 	}
 
-	if len(text) == 0 {
-		return nil, fmt.Errorf("empty text")
+	img, err := regionImage(img, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
 	}
 
-	if opts == nil {
-		opts = &EncodeOptions{
-			Width:  256,
-			Height: 256,
-			Format: "QR_CODE",
-		}
+	data, width, height := imageToGray(img)
+	if err := validatePixels(data, width, height, 1); err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
 	}
 
-	// 调用 WASM 编码函数
-	result, err := w.runtime.EncodeText(text, opts.Width, opts.Height)
+	results, err := w.decodeMultiPixels(ctx, data, width, height, 1, opts)
 	if err != nil {
-		return nil, fmt.Errorf("WASM encode failed: %w", err)
+		return nil, err
 	}
 
-	if !result.Success {
-		return nil, fmt.Errorf("encode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+	offsetPoints(results, img.Bounds().Min)
+	return results, nil
+}
+
+// DecodeMultiBytes 解码字节数据中的所有条码
+func (w *wasmZXing) DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	if _, err := w.ready(ctx, "decode"); err != nil {
+		return nil, err
+	}
+
+	pixels, rect, err := rgbaRegion(data, width, height, opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "decode", ErrInvalidInput, err)
+	}
+
+	results, err := w.decodeMultiPixels(ctx, pixels, rect.Dx(), rect.Dy(), 4, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetPoints(results, rect.Min)
+	return results, nil
+}
+
+// decodeMultiPixels 解码指定通道数的像素数据中的所有条码
+func (w *wasmZXing) decodeMultiPixels(ctx context.Context, data []byte, width, height, channels int, opts *DecodeOptions) ([]*Result, error) {
+	if err := checkDecodeOptions(opts); err != nil {
+		return nil, err
+	}
+
+	// JS 调用同步执行，无法中途取消，只能在调用前检查 ctx
+	rt, err := w.ready(ctx, "decode")
+	if err != nil {
+		return nil, err
+	}
+
+	// 调用 WASM 多码解码函数，失败时胶水代码返回只含一个失败结果的数组
+	decoded, err := rt.DecodeMultiple(data, width, height, channels)
+	if err != nil {
+		return nil, wrapRuntimeError(ctx, "decode", err)
+	}
+
+	results := make([]*Result, 0, len(decoded))
+	for _, result := range decoded {
+		if result == nil {
+			continue
+		}
+		if !result.Success {
+			return nil, newError(BackendWASM, "decode", kindFromCode(result.ErrorCode),
+				fmt.Errorf("decode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode))
+		}
+		results = append(results, convertJSResult(result))
+	}
+	if len(results) == 0 {
+		return nil, newError(BackendWASM, "decode", ErrNotFound, nil)
+	}
+
+	return limitResults(results, opts), nil
+}
+
+// checkDecodeOptions 胶水代码不接受解码选项，与 DefaultDecodeOptions() 不同的选项
+// 返回 ErrUnsupportedFormat。Region、Stride 和 MaxSymbols 在 Go 中处理，不受限制
+func checkDecodeOptions(opts *DecodeOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	var name string
+	switch {
+	case !opts.TryHarder:
+		name = "TryHarder"
+	case !tryRotate(opts):
+		name = "TryRotate"
+	case opts.TryInvert:
+		name = "TryInvert"
+	case !tryDownscale(opts):
+		name = "TryDownscale"
+	case opts.Binarizer != BinarizerLocalAverage:
+		name = "Binarizer"
+	case opts.DownscaleThreshold != 0:
+		name = "DownscaleThreshold"
+	case opts.DownscaleFactor != 0:
+		name = "DownscaleFactor"
+	case opts.IsPure:
+		name = "IsPure"
+	case opts.MinLineCount != 0:
+		name = "MinLineCount"
+	case possibleFormats(opts) != FormatAll:
+		name = "PossibleFormats"
+	case opts.CharacterSet != "":
+		name = "CharacterSet"
+	case opts.ReturnErrors:
+		name = "ReturnErrors"
+	case opts.TextMode != TextModeHRI:
+		name = "TextMode"
+	case opts.EANAddOn != EANAddOnIgnore:
+		name = "EANAddOn"
+	case opts.Symbology != nil && !isDefaultSymbology(opts.Symbology):
+		name = "Symbology"
+	default:
+		return nil
+	}
+	return newError(BackendWASM, "decode", ErrUnsupportedFormat,
+		fmt.Errorf("js/wasm backend does not support DecodeOptions.%s", name))
+}
+
+// isDefaultSymbology 判断 sym 是否与 DefaultSymbologyOptions() 行为一致
+func isDefaultSymbology(sym *SymbologyOptions) bool {
+	return code39ExtendedMode(sym) && codabarStartStop(sym) && !sym.Code39CheckDigit &&
+		!sym.ITFCheckDigit && sym.ITFMinLength == 0 && sym.ITFMaxLength == 0
+}
+
+// wrapRuntimeError 对 JS 运行时返回的错误分类
+func wrapRuntimeError(ctx context.Context, op string, err error) error {
+	if errors.Is(err, wasm.ErrUnsupported) {
+		return newError(BackendWASM, op, ErrUnsupportedFormat, err)
+	}
+	return wrapError(ctx, BackendWASM, op, err)
+}
+
+// convertJSResult 将 wasm.DecodeResult 转换为公共 Result 类型，角点为解码像素中的坐标
+func convertJSResult(result *wasm.DecodeResult) *Result {
+	return &Result{
+		Text:   result.Text,
+		Format: formatFromName(result.Format),
+		Points: append([]image.Point(nil), result.Points...),
+
+		SymbologyIdentifier: result.SymbologyIdentifier,
+		ECLevel:             result.ECLevel,
+		Version:             result.Version,
+		Orientation:         result.Orientation,
+		IsMirrored:          result.IsMirrored,
+		IsInverted:          result.IsInverted,
+		LineCount:           result.LineCount,
+		ContentType:         ContentType(result.ContentType),
+		HasECI:              result.HasECI,
+		Bytes:               result.Bytes,
+		ECISegments:         parseECISegments(result.BytesECI, len(result.SymbologyIdentifier)),
+		StructuredAppend:    newStructuredAppend(result.SequenceSize, result.SequenceIndex, result.SequenceID),
+		Error:               symbolError(BackendWASM, result.ErrorCode, result.ErrorMessage),
+		AddOn:               result.AddOn,
+		Metadata:            make(map[string]interface{}),
+	}
+}
+
+// EncodeText 编码文本为条码图像
+func (w *wasmZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	result, err := w.encode(ctx, text, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// 转换字节数据为图像
@@ -122,39 +302,106 @@ func (w *wasmZXing) EncodeText(ctx context.Context, text string, opts *EncodeOpt
 
 // EncodeToBytes 编码文本为字节数据
 func (w *wasmZXing) EncodeToBytes(ctx context.Context, text string, opts *EncodeOptions) ([]byte, int, int, error) {
-	if !w.runtime.IsReady() {
-		return nil, 0, 0, fmt.Errorf("WASM runtime not ready")
+	ctx, cancel := withTimeout(ctx, w.config)
+	defer cancel()
+
+	result, err := w.encode(ctx, text, opts)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return result.Data, result.Width, result.Height, nil
+}
+
+// encode 调用 WASM 编码函数。胶水代码只导出 encode_text_to_qr，
+// 因此只支持 QR Code，且不支持指定纠错级别和静区
+func (w *wasmZXing) encode(ctx context.Context, text string, opts *EncodeOptions) (*wasm.EncodeResult, error) {
+	if _, err := w.ready(ctx, "encode"); err != nil {
+		return nil, err
 	}
 
 	if len(text) == 0 {
-		return nil, 0, 0, fmt.Errorf("empty text")
+		return nil, newError(BackendWASM, "encode", ErrInvalidInput, fmt.Errorf("empty text"))
 	}
 
 	if opts == nil {
 		opts = &EncodeOptions{
 			Width:  256,
 			Height: 256,
-			Format: "QR_CODE",
+			Format: FormatQRCode,
 		}
 	}
 
+	format, ecLevel, err := encodeParams(opts)
+	if err != nil {
+		return nil, newError(BackendWASM, "encode", ErrInvalidInput, err)
+	}
+	switch {
+	case format != FormatQRCode:
+		return nil, newError(BackendWASM, "encode", ErrUnsupportedFormat,
+			fmt.Errorf("js/wasm backend only encodes QR Code, got %s", format))
+	case ecLevel != "":
+		return nil, newError(BackendWASM, "encode", ErrUnsupportedFormat,
+			fmt.Errorf("js/wasm backend does not support ErrorCorrectionLevel"))
+	case opts.Margin != 0:
+		return nil, newError(BackendWASM, "encode", ErrUnsupportedFormat,
+			fmt.Errorf("js/wasm backend does not support Margin"))
+	}
+
+	// JS 调用同步执行，无法中途取消，只能在调用前检查 ctx
+	rt, err := w.ready(ctx, "encode")
+	if err != nil {
+		return nil, err
+	}
+
 	// 调用 WASM 编码函数
-	result, err := w.runtime.EncodeText(text, opts.Width, opts.Height)
+	result, err := rt.EncodeText(text, opts.Width, opts.Height)
 	if err != nil {
-		return nil, 0, 0, fmt.Errorf("WASM encode failed: %w", err)
+		return nil, wrapRuntimeError(ctx, "encode", err)
 	}
 
 	if !result.Success {
-		return nil, 0, 0, fmt.Errorf("encode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode)
+		return nil, newError(BackendWASM, "encode", kindFromCode(result.ErrorCode),
+			fmt.Errorf("encode failed: %s (code: %d)", result.ErrorMessage, result.ErrorCode))
 	}
 
-	return result.Data, result.Width, result.Height, nil
+	return result, nil
+}
+
+// ready 检查 ctx 并返回运行时，首次使用时加载 WASM 模块，加载失败的下次调用会重试。
+// 已取消的 ctx 优先返回 ErrCanceled
+func (w *wasmZXing) ready(ctx context.Context, op string) (*wasm.Runtime, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, newError(BackendWASM, op, ErrCanceled, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.runtime.IsReady() {
+		return w.runtime, nil
+	}
+
+	rt := wasm.NewRuntime()
+	if err := rt.Initialize(ctx, w.config.WASMPath); err != nil {
+		err = fmt.Errorf("failed to initialize WASM runtime: %w", err)
+		if ctx.Err() != nil {
+			return nil, newError(BackendWASM, op, ErrCanceled, err)
+		}
+		return nil, newError(BackendWASM, op, ErrBackendUnavailable, err)
+	}
+	w.runtime = rt
+	return rt, nil
 }
 
 // Close 关闭资源
 func (w *wasmZXing) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if w.runtime != nil {
-		return w.runtime.Close()
+		err := w.runtime.Close()
+		w.runtime = nil
+		return err
 	}
 	return nil
 }
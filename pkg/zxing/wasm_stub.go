@@ -6,7 +6,7 @@ package zxing
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"image"
 )
 
@@ -17,22 +17,32 @@ type wasmZXing struct {
 
 // DecodeImage returns an error on CGO platforms.
 func (w *wasmZXing) DecodeImage(ctx context.Context, img image.Image, opts *DecodeOptions) (*Result, error) {
-	return nil, fmt.Errorf("WASM backend is not available when CGO is enabled on linux/windows")
+	return nil, wasmUnavailableError("decode")
 }
 
 // DecodeBytes returns an error on CGO platforms.
 func (w *wasmZXing) DecodeBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) (*Result, error) {
-	return nil, fmt.Errorf("WASM backend is not available when CGO is enabled on linux/windows")
+	return nil, wasmUnavailableError("decode")
+}
+
+// DecodeMultiImage returns an error on CGO platforms.
+func (w *wasmZXing) DecodeMultiImage(ctx context.Context, img image.Image, opts *DecodeOptions) ([]*Result, error) {
+	return nil, wasmUnavailableError("decode")
+}
+
+// DecodeMultiBytes returns an error on CGO platforms.
+func (w *wasmZXing) DecodeMultiBytes(ctx context.Context, data []byte, width, height int, opts *DecodeOptions) ([]*Result, error) {
+	return nil, wasmUnavailableError("decode")
 }
 
 // EncodeText returns an error on CGO platforms.
 func (w *wasmZXing) EncodeText(ctx context.Context, text string, opts *EncodeOptions) (image.Image, error) {
-	return nil, fmt.Errorf("WASM backend is not available when CGO is enabled on linux/windows")
+	return nil, wasmUnavailableError("encode")
 }
 
 // EncodeToBytes returns an error on CGO platforms.
 func (w *wasmZXing) EncodeToBytes(ctx context.Context, text string, opts *EncodeOptions) ([]byte, int, int, error) {
-	return nil, 0, 0, fmt.Errorf("WASM backend is not available when CGO is enabled on linux/windows")
+	return nil, 0, 0, wasmUnavailableError("encode")
 }
 
 // Close is a no-op stub.
@@ -44,3 +54,11 @@ func (w *wasmZXing) Close() error {
 func (w *wasmZXing) GetBackend() Backend {
 	return BackendWASM
 }
+
+// errWASMUnavailable is the cause reported by every stub in this file.
+var errWASMUnavailable = errors.New("WASM backend is not available when CGO is enabled on linux/windows")
+
+// wasmUnavailableError returns an ErrBackendUnavailable error for op.
+func wasmUnavailableError(op string) error {
+	return newError(BackendWASM, op, ErrBackendUnavailable, errWASMUnavailable)
+}
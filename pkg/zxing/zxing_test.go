@@ -2,13 +2,73 @@ package zxing
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	_ "image/png"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+// skipJSBackend skips tests that decode real symbols or set non-default decode
+// options on js/wasm: the glue takes no decode options, and go test under node
+// does not load the zxing.js module the backend needs.
+func skipJSBackend(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "js" {
+		t.Skip("js/wasm backend takes no decode options and needs zxing.js, which go test does not load")
+	}
+}
+
+// testWASMPath is the WASM module the wazero backend loads in tests.
+const testWASMPath = "../../wasm/zxingwrapper.wasm"
+
+// newTestZXing creates an instance of the default backend for this build and
+// closes it when the test ends.
+func newTestZXing(t *testing.T) ZXing {
+	t.Helper()
+	config := DefaultConfig()
+	config.WASMPath = testWASMPath
+	return newTestZXingConfig(t, config)
+}
+
+// newTestZXingConfig creates an instance from config and closes it when the
+// test ends.
+func newTestZXingConfig(t *testing.T, config *Config) ZXing {
+	t.Helper()
+	zx, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create ZXing instance: %v", err)
+	}
+	t.Cleanup(func() { zx.Close() })
+	return zx
+}
+
+// loadTestImage decodes an image from the data directory, skipping the test
+// when the file is missing.
+func loadTestImage(t *testing.T, name string) image.Image {
+	t.Helper()
+	file, err := os.Open(filepath.Join("../../data", name))
+	if err != nil {
+		t.Skipf("test image not found: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		t.Fatalf("failed to decode test image: %v", err)
+	}
+	return img
+}
+
 func TestNewZXing(t *testing.T) {
 	config := DefaultConfig()
 	config.Backend = BackendAuto
@@ -60,59 +120,1541 @@ func TestBackendSelection(t *testing.T) {
 }
 
 func TestDecodeQRCodeImage(t *testing.T) {
-	config := DefaultConfig()
-	config.Backend = BackendAuto
-	config.WASMPath = "../../wasm/zxingwrapper.wasm"
+	skipJSBackend(t)
 
-	zx, err := New(config)
+	zx := newTestZXing(t)
+
+	img := loadTestImage(t, "qrcode_www.bing.com.png")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := zx.DecodeImage(ctx, img, &DecodeOptions{TryHarder: true})
 	if err != nil {
-		t.Fatalf("failed to create ZXing instance: %v", err)
+		t.Fatalf("failed to decode QR code: %v", err)
 	}
-	defer zx.Close()
 
-	// Load a real QR code test image
-	file, err := os.Open("../../data/qrcode_www.bing.com.png")
+	if len(result.Text) == 0 {
+		t.Error("decoded text should not be empty")
+	}
+	if !strings.HasPrefix(result.SymbologyIdentifier, "]Q") {
+		t.Errorf("unexpected symbology identifier: %q", result.SymbologyIdentifier)
+	}
+	if result.ECLevel == "" || result.Version == "" {
+		t.Errorf("expected EC level and version, got %q and %q", result.ECLevel, result.Version)
+	}
+	if result.ContentType != ContentTypeText {
+		t.Errorf("expected content type %s, got %s", ContentTypeText, result.ContentType)
+	}
+	if string(result.Bytes) != result.Text {
+		t.Errorf("raw bytes %q do not match text %q", result.Bytes, result.Text)
+	}
+
+	t.Logf("Decoded text: %s, format: %s, backend: %s", result.Text, result.Format, zx.GetBackend())
+}
+
+func TestDecodeQRCodePoints(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	img := loadTestImage(t, "qrcode_www.bing.com.png")
+
+	// Embed the QR code in a larger canvas and decode a sub-image whose
+	// bounds do not start at the origin.
+	b := img.Bounds()
+	offset := image.Pt(40, 25)
+	canvas := image.NewRGBA(image.Rect(0, 0, b.Dx()+2*offset.X, b.Dy()+2*offset.Y))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, b.Sub(b.Min).Add(offset), img, b.Min, draw.Src)
+	sub := canvas.SubImage(image.Rect(offset.X/2, offset.Y/2, canvas.Bounds().Dx(), canvas.Bounds().Dy()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := zx.DecodeImage(ctx, sub, &DecodeOptions{TryHarder: true})
 	if err != nil {
-		t.Skipf("test image not found: %v", err)
+		t.Fatalf("failed to decode QR code: %v", err)
+	}
+	if len(result.Points) != 4 {
+		t.Fatalf("expected 4 points, got %d", len(result.Points))
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	// Every corner must lie inside the drawn symbol, in sub-image coordinates.
+	symbol := b.Sub(b.Min).Add(offset)
+	for i, p := range result.Points {
+		if !p.In(symbol.Inset(-2)) {
+			t.Errorf("point %d %v is outside the symbol area %v", i, p, symbol)
+		}
+	}
+}
+
+func TestDecodeInvertedQRCode(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	img := loadTestImage(t, "qrcode_www.bing.com.png")
+
+	// White-on-black copy of the test image
+	bounds := img.Bounds()
+	inverted := image.NewGray(bounds)
+	draw.Draw(inverted, bounds, img, bounds.Min, draw.Src)
+	for i := range inverted.Pix {
+		inverted.Pix[i] = 0xFF - inverted.Pix[i]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := DefaultDecodeOptions()
+	if _, err := zx.DecodeImage(ctx, inverted, opts); err == nil {
+		t.Error("expected inverted code to be missed with TryInvert disabled")
+	}
+
+	opts.TryInvert = true
+	result, err := zx.DecodeImage(ctx, inverted, opts)
 	if err != nil {
-		t.Fatalf("failed to decode test image: %v", err)
+		t.Fatalf("failed to decode inverted QR code: %v", err)
+	}
+	if !result.IsInverted {
+		t.Error("expected IsInverted to be set")
 	}
+}
+
+func TestDecodeDamagedQRCode(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := zx.DecodeImage(ctx, img, &DecodeOptions{TryHarder: true})
+	img, err := zx.EncodeText(ctx, "https://example.com/qa/station/07/lot/2026-10-18/serial/0000123456789",
+		&EncodeOptions{Format: FormatQRCode, Width: 400, Height: 400, ErrorCorrectionLevel: "L"})
 	if err != nil {
-		t.Fatalf("failed to decode QR code: %v", err)
+		t.Fatalf("failed to encode: %v", err)
 	}
 
-	if len(result.Text) == 0 {
-		t.Error("decoded text should not be empty")
+	// Invert the middle of the symbol: the finder patterns survive but the data
+	// region is damaged beyond what error correction can recover.
+	gray := img.(*image.Gray)
+	bounds := gray.Bounds()
+	for y := bounds.Dy() * 3 / 10; y < bounds.Dy()*7/10; y++ {
+		for x := bounds.Dx() * 3 / 10; x < bounds.Dx()*7/10; x++ {
+			i := gray.PixOffset(x, y)
+			gray.Pix[i] = 0xFF - gray.Pix[i]
+		}
 	}
 
-	t.Logf("Decoded text: %s, format: %s, backend: %s", result.Text, result.Format, zx.GetBackend())
+	opts := DefaultDecodeOptions()
+	if _, err := zx.DecodeImage(ctx, gray, opts); err == nil {
+		t.Fatal("expected damaged code to fail without ReturnErrors")
+	}
+
+	opts.ReturnErrors = true
+	result, err := zx.DecodeImage(ctx, gray, opts)
+	if err != nil {
+		t.Fatalf("expected damaged code to be returned with ReturnErrors: %v", err)
+	}
+	if result.IsValid() || result.Error == nil {
+		t.Fatal("expected result to carry a decode error")
+	}
+	if !errors.Is(result.Error, ErrChecksum) && !errors.Is(result.Error, ErrFormat) {
+		t.Errorf("unexpected error kind: %v", result.Error)
+	}
+	if result.Format != FormatQRCode {
+		t.Errorf("expected format %s, got %s", FormatQRCode, result.Format)
+	}
+	if len(result.Points) != 4 || result.Points[0] == result.Points[2] {
+		t.Errorf("expected the symbol position, got %v", result.Points)
+	}
+
+	results, err := zx.DecodeMultiImage(ctx, gray, opts)
+	if err != nil {
+		t.Fatalf("failed to decode with ReturnErrors in multi mode: %v", err)
+	}
+	if len(results) != 1 || results[0].Error == nil {
+		t.Errorf("expected one damaged result, got %d", len(results))
+	}
 }
 
-func TestConfigFromEnv(t *testing.T) {
-	config := DefaultConfig()
+func TestSymbolError(t *testing.T) {
+	if err := symbolError(BackendCGO, codeNone, ""); err != nil {
+		t.Errorf("expected nil for a valid symbol, got %v", err)
+	}
 
-	if config.Backend != BackendAuto {
-		t.Errorf("expected default backend %s, got %s", BackendAuto, config.Backend)
+	err := symbolError(BackendWASM, codeChecksum, "ChecksumError")
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+	if err.Error() != "zxing wasm decode: ChecksumError" {
+		t.Errorf("unexpected message %q", err.Error())
 	}
 
-	if config.Timeout != 30 {
-		t.Errorf("expected default timeout 30, got %d", config.Timeout)
+	// Unknown codes are still reported as a damaged symbol
+	if err := symbolError(BackendCGO, codeInternal, ""); !errors.Is(err, ErrFormat) {
+		t.Errorf("expected ErrFormat for an unclassified failure, got %v", err)
 	}
 
-	if config.Debug != false {
-		t.Errorf("expected default debug false, got %t", config.Debug)
+	if !(&Result{}).IsValid() || (&Result{Error: err}).IsValid() {
+		t.Error("IsValid should report whether Error is nil")
 	}
+}
 
-	if config.WASMPath != "wasm/zxingwrapper.wasm" {
-		t.Errorf("expected default WASM path wasm/zxingwrapper.wasm, got %s", config.WASMPath)
+func TestOffsetPoints(t *testing.T) {
+	results := []*Result{
+		{Points: []image.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}},
+		nil,
+		{},
+	}
+	offsetPoints(results, image.Pt(10, 20))
+
+	want := []image.Point{{X: 11, Y: 22}, {X: 13, Y: 24}}
+	for i, p := range results[0].Points {
+		if p != want[i] {
+			t.Errorf("point %d = %v, want %v", i, p, want[i])
+		}
+	}
+}
+
+// opaqueImage hides the concrete type of an image so imageToGray takes its
+// generic img.At path.
+type opaqueImage struct{ image.Image }
+
+// testPattern fills a w x h RGBA image with a deterministic, partly translucent pattern.
+func testPattern(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 13), B: uint8(x ^ y), A: uint8(0xff - x%3*40)})
+		}
+	}
+	return img
+}
+
+func TestImageToGray(t *testing.T) {
+	src := testPattern(40, 30)
+	rect := image.Rect(5, 3, 33, 27)
+
+	gray := image.NewGray(src.Bounds())
+	draw.Draw(gray, gray.Bounds(), src, image.Point{}, draw.Src)
+	nrgba := image.NewNRGBA(src.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), src, image.Point{}, draw.Src)
+	paletted := image.NewPaletted(src.Bounds(), color.Palette{color.Black, color.White, color.RGBA{R: 0x80, G: 0x20, B: 0x40, A: 0xff}})
+	draw.Draw(paletted, paletted.Bounds(), src, image.Point{}, draw.Src)
+	ycbcr := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = 0x80, 0x80
+	}
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			ycbcr.Y[ycbcr.YOffset(x, y)] = uint8(x*5 + y)
+		}
+	}
+
+	images := map[string]image.Image{
+		"gray":     gray,
+		"rgba":     src,
+		"nrgba":    nrgba,
+		"paletted": paletted,
+		"ycbcr":    ycbcr,
+	}
+	for name, img := range images {
+		for _, sub := range []bool{false, true} {
+			if sub {
+				img = img.(interface {
+					SubImage(image.Rectangle) image.Image
+				}).SubImage(rect)
+			}
+			got, w, h := imageToGray(img)
+			want, ww, wh := imageToGray(opaqueImage{img})
+			if w != ww || h != wh {
+				t.Fatalf("%s (sub=%v): size %dx%d, want %dx%d", name, sub, w, h, ww, wh)
+			}
+			if name == "ycbcr" {
+				// The generic path converts YCbCr back to RGB; only the Y plane is exact.
+				for i := range want {
+					if d := int(got[i]) - int(want[i]); d < -2 || d > 2 {
+						t.Fatalf("%s (sub=%v): pixel %d = %d, want about %d", name, sub, i, got[i], want[i])
+					}
+				}
+				continue
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s (sub=%v): fast path differs from generic path", name, sub)
+			}
+		}
+	}
+
+	// A tightly packed gray image is passed through without copying.
+	if data, _, _ := imageToGray(gray); &data[0] != &gray.Pix[0] {
+		t.Error("expected tightly packed gray image to be passed through")
+	}
+}
+
+func TestDecodeSubImage(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	symbol, err := zx.EncodeText(ctx, "sub image", &EncodeOptions{Format: FormatQRCode, Width: 200, Height: 200})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// Place the symbol in the lower right of a larger canvas filled with noise,
+	// then decode only that region.
+	canvas := testPattern(400, 300)
+	offset := image.Pt(180, 90)
+	region := symbol.Bounds().Add(offset)
+	draw.Draw(canvas, region, symbol, symbol.Bounds().Min, draw.Src)
+
+	gray := image.NewGray(canvas.Bounds())
+	draw.Draw(gray, gray.Bounds(), canvas, image.Point{}, draw.Src)
+
+	for name, img := range map[string]image.Image{
+		"rgba": canvas.SubImage(region),
+		"gray": gray.SubImage(region),
+	} {
+		result, err := zx.DecodeImage(ctx, img, &DecodeOptions{PossibleFormats: FormatQRCode})
+		if err != nil {
+			t.Fatalf("%s: failed to decode sub image: %v", name, err)
+		}
+		if result.Text != "sub image" {
+			t.Errorf("%s: decoded %q", name, result.Text)
+		}
+		for _, p := range result.Points {
+			if !p.In(region) {
+				t.Errorf("%s: point %v outside region %v", name, p, region)
+			}
+		}
+	}
+}
+
+func TestRGBARegion(t *testing.T) {
+	// 4x3 pixels, rows padded to 20 bytes; each pixel's R holds x+10*y.
+	const width, height, stride = 4, 3, 20
+	data := make([]byte, stride*(height-1)+width*4)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			data[y*stride+x*4] = byte(x + 10*y)
+		}
+	}
+
+	pixels, rect, err := rgbaRegion(data, width, height, &DecodeOptions{Stride: stride, Region: image.Rect(1, 1, 3, 9)})
+	if err != nil {
+		t.Fatalf("rgbaRegion: %v", err)
+	}
+	if rect != image.Rect(1, 1, 3, 3) {
+		t.Errorf("rect = %v, want clipped to (1,1)-(3,3)", rect)
+	}
+	want := []byte{11, 12, 21, 22}
+	for i, v := range want {
+		if pixels[i*4] != v {
+			t.Errorf("pixel %d = %d, want %d", i, pixels[i*4], v)
+		}
+	}
+
+	// Full-width regions of tightly packed data are not copied.
+	tight := make([]byte, width*height*4)
+	pixels, _, err = rgbaRegion(tight, width, height, &DecodeOptions{Region: image.Rect(0, 1, width, 3)})
+	if err != nil {
+		t.Fatalf("rgbaRegion: %v", err)
+	}
+	if &pixels[0] != &tight[width*4] {
+		t.Error("expected full-width region to share the input buffer")
+	}
+
+	for name, opts := range map[string]*DecodeOptions{
+		"short stride":   {Stride: width*4 - 1},
+		"short data":     {Stride: stride + 4},
+		"outside region": {Region: image.Rect(10, 10, 20, 20)},
+	} {
+		if _, _, err := rgbaRegion(data, width, height, opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRegionImage(t *testing.T) {
+	img := testPattern(40, 30)
+
+	sub, err := regionImage(opaqueImage{img}, &DecodeOptions{Region: image.Rect(30, 20, 50, 50)})
+	if err != nil {
+		t.Fatalf("regionImage: %v", err)
+	}
+	if got := sub.Bounds(); got != image.Rect(30, 20, 40, 30) {
+		t.Errorf("bounds = %v, want clipped to (30,20)-(40,30)", got)
+	}
+	if sub.At(35, 25) != img.At(35, 25) {
+		t.Error("region view should read the original pixels")
+	}
+
+	if _, err := regionImage(img, &DecodeOptions{Region: image.Rect(-10, -10, -1, -1)}); err == nil {
+		t.Error("expected error for region outside the image")
+	}
+}
+
+func TestDecodeRegion(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	symbol, err := zx.EncodeText(ctx, "region", &EncodeOptions{Format: FormatQRCode, Width: 200, Height: 200})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// Two symbols side by side; the region selects the right one only.
+	canvas := image.NewRGBA(image.Rect(0, 0, 500, 240))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, symbol.Bounds().Add(image.Pt(20, 20)), symbol, symbol.Bounds().Min, draw.Src)
+	right := symbol.Bounds().Add(image.Pt(280, 20))
+	draw.Draw(canvas, right, symbol, symbol.Bounds().Min, draw.Src)
+
+	opts := &DecodeOptions{PossibleFormats: FormatQRCode, Region: right}
+	results, err := zx.DecodeMultiImage(ctx, canvas, opts)
+	if err != nil {
+		t.Fatalf("failed to decode region: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result inside the region, got %d", len(results))
+	}
+	for _, p := range results[0].Points {
+		if !p.In(right) {
+			t.Errorf("point %v outside region %v", p, right)
+		}
+	}
+
+	// The same region from a padded raw buffer.
+	stride := canvas.Stride + 64
+	padded := make([]byte, stride*canvas.Rect.Dy())
+	for y := 0; y < canvas.Rect.Dy(); y++ {
+		copy(padded[y*stride:], canvas.Pix[y*canvas.Stride:(y+1)*canvas.Stride])
+	}
+	opts.Stride = stride
+	result, err := zx.DecodeBytes(ctx, padded, canvas.Rect.Dx(), canvas.Rect.Dy(), opts)
+	if err != nil {
+		t.Fatalf("failed to decode padded bytes: %v", err)
+	}
+	if result.Text != "region" {
+		t.Errorf("decoded %q", result.Text)
+	}
+	for _, p := range result.Points {
+		if !p.In(right) {
+			t.Errorf("point %v outside region %v", p, right)
+		}
+	}
+
+	opts.Region = image.Rect(600, 0, 700, 100)
+	if _, err := zx.DecodeBytes(ctx, padded, canvas.Rect.Dx(), canvas.Rect.Dy(), opts); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for region outside the image, got %v", err)
+	}
+}
+
+func TestDecodeMultiQRCodeImage(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	img := loadTestImage(t, "qrcode_www.bing.com.png")
+
+	// Place three copies of the QR code side by side
+	b := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, b.Dx()*3, b.Dy()))
+	for i := 0; i < 3; i++ {
+		draw.Draw(canvas, b.Sub(b.Min).Add(image.Pt(b.Dx()*i, 0)), img, b.Min, draw.Src)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results, err := zx.DecodeMultiImage(ctx, canvas, &DecodeOptions{TryHarder: true})
+	if err != nil {
+		t.Fatalf("failed to decode QR codes: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if len(result.Text) == 0 {
+			t.Error("decoded text should not be empty")
+		}
+	}
+
+	limited, err := zx.DecodeMultiImage(ctx, canvas, &DecodeOptions{TryHarder: true, MaxSymbols: 2})
+	if err != nil {
+		t.Fatalf("failed to decode QR codes with MaxSymbols: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 results with MaxSymbols=2, got %d", len(limited))
+	}
+}
+
+func TestLimitResults(t *testing.T) {
+	results := []*Result{{Text: "a"}, {Text: "b"}, {Text: "c"}}
+
+	tests := []struct {
+		name string
+		opts *DecodeOptions
+		want int
+	}{
+		{"nil options", nil, 3},
+		{"no limit", &DecodeOptions{}, 3},
+		{"limit below count", &DecodeOptions{MaxSymbols: 2}, 2},
+		{"limit above count", &DecodeOptions{MaxSymbols: 5}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(limitResults(results, tt.opts)); got != tt.want {
+				t.Errorf("limitResults returned %d results, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseECISegments(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		prefix int
+		want   []ECISegment
+	}{
+		{"empty", "", 0, nil},
+		{"no ECI", "]Q1hello", 3, []ECISegment{{ECI: -1, Data: []byte("hello")}}},
+		{"escaped backslash", `]Q1a\\b`, 3, []ECISegment{{ECI: -1, Data: []byte(`a\b`)}}},
+		{
+			"multiple ECI",
+			"]Q2\\000026caf\xc3\xa9\\000899\x00\x01",
+			3,
+			[]ECISegment{
+				{ECI: 26, Charset: "UTF-8", Data: []byte("caf\xc3\xa9")},
+				{ECI: 899, Charset: "Binary", Data: []byte{0, 1}},
+			},
+		},
+		{"short designator", `]Q1x\12`, 3, []ECISegment{{ECI: -1, Data: []byte(`x\12`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseECISegments([]byte(tt.input), tt.prefix)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d segments, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i].ECI != tt.want[i].ECI || got[i].Charset != tt.want[i].Charset ||
+					string(got[i].Data) != string(tt.want[i].Data) {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReassembleStructuredAppend(t *testing.T) {
+	part := func(format Format, id string, index, count int, text string) *Result {
+		return &Result{
+			Text:             text,
+			Format:           format,
+			Bytes:            []byte(text),
+			StructuredAppend: &StructuredAppend{Index: index, Count: count, ID: id},
+		}
+	}
+
+	results := []*Result{
+		part(FormatQRCode, "17", 2, 3, "baz"),
+		{Text: "standalone", Format: FormatQRCode},
+		part(FormatQRCode, "17", 0, 3, "foo"),
+		part(FormatQRCode, "42", 1, 4, "b"),
+		part(FormatQRCode, "17", 1, 3, "bar"),
+		part(FormatQRCode, "17", 1, 3, "bar"), // 来自另一张图片的重复条码
+		part(FormatPDF417, "", 1, 0, "2"),
+		part(FormatPDF417, "", 0, 0, "1"),
+	}
+
+	messages := ReassembleStructuredAppend(results)
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+
+	complete := messages[0]
+	if !complete.Complete || complete.ID != "17" || len(complete.Parts) != 3 {
+		t.Errorf("unexpected complete message: %+v", complete)
+	}
+	if complete.Text != "foobarbaz" || string(complete.Bytes) != "foobarbaz" {
+		t.Errorf("unexpected payload: %q / %q", complete.Text, complete.Bytes)
+	}
+
+	partial := messages[1]
+	if partial.Complete || len(partial.Missing) != 3 ||
+		partial.Missing[0] != 0 || partial.Missing[1] != 2 || partial.Missing[2] != 3 {
+		t.Errorf("unexpected partial message: complete=%t missing=%v", partial.Complete, partial.Missing)
+	}
+
+	unknown := messages[2]
+	if unknown.Complete || len(unknown.Missing) != 0 || unknown.Text != "12" {
+		t.Errorf("unexpected message with unknown count: complete=%t missing=%v text=%q",
+			unknown.Complete, unknown.Missing, unknown.Text)
+	}
+}
+
+func TestNewStructuredAppend(t *testing.T) {
+	if sa := newStructuredAppend(-1, -1, ""); sa != nil {
+		t.Errorf("expected nil for a symbol outside a sequence, got %+v", sa)
+	}
+	sa := newStructuredAppend(4, 1, "17")
+	if sa == nil || sa.Index != 1 || sa.Count != 4 || sa.ID != "17" {
+		t.Fatalf("unexpected structured append info: %+v", sa)
+	}
+	if got := sa.String(); got != "2/4 (id 17)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr bool
+	}{
+		{"QR_CODE", FormatQRCode, false},
+		{"QRCode", FormatQRCode, false},
+		{"qr-code", FormatQRCode, false},
+		{"QR Code", FormatQRCode, false},
+		{"Code128", FormatCode128, false},
+		{"PDF417", FormatPDF417, false},
+		{"ean_13", FormatEAN13, false},
+		{"UPC-A", FormatUPCA, false},
+		{"DataBarExpanded", FormatDataBarExpanded, false},
+		{"micro-qr", FormatMicroQRCode, false},
+		{"rMQR Code", FormatRMQRCode, false},
+		{"DX_FILM_EDGE", FormatDXFilmEdge, false},
+		{"all", FormatAll, false},
+		{"NOPE", FormatNone, true},
+		{"", FormatNone, true},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat(%q) error = %v, wantErr %t", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	got, err := ParseFormats("QR_CODE, ean-13|DataMatrix")
+	if err != nil {
+		t.Fatalf("ParseFormats failed: %v", err)
+	}
+	want := FormatQRCode | FormatEAN13 | FormatDataMatrix
+	if got != want {
+		t.Errorf("ParseFormats = %s, want %s", got, want)
+	}
+	if got.String() != "QR_CODE|DATA_MATRIX|EAN_13" {
+		t.Errorf("unexpected String(): %q", got.String())
+	}
+	if !got.Has(FormatEAN13) || got.Has(FormatCode39) {
+		t.Errorf("unexpected Has results for %s", got)
+	}
+
+	if _, err := ParseFormats("QR_CODE,BOGUS"); err == nil {
+		t.Error("expected an error for an unknown format name")
+	}
+	if f, err := ParseFormats(""); err != nil || f != FormatNone {
+		t.Errorf("ParseFormats(\"\") = %s, %v", f, err)
+	}
+}
+
+func TestFormatStringRoundTrip(t *testing.T) {
+	for _, f := range FormatAll.Formats() {
+		parsed, err := ParseFormat(f.String())
+		if err != nil || parsed != f {
+			t.Errorf("round trip of %s gave %s, %v", f, parsed, err)
+		}
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	zx := newTestZXing(t)
+
+	tests := []struct {
+		text string
+		opts *EncodeOptions
+	}{
+		{"https://example.com/manifest/42", nil},
+		{"HELLO-QR", &EncodeOptions{Format: FormatQRCode, Width: 200, Height: 200, ErrorCorrectionLevel: "H"}},
+		{"datamatrix payload", &EncodeOptions{Format: FormatDataMatrix, Width: 120, Height: 120}},
+		{"CODE128-0042", &EncodeOptions{Format: FormatCode128, Width: 400, Height: 100, Margin: 20}},
+		{"5901234123457", &EncodeOptions{Format: FormatEAN13, Width: 300, Height: 120}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			img, err := zx.EncodeText(ctx, tt.text, tt.opts)
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			if _, ok := img.(*image.Gray); !ok {
+				t.Errorf("expected *image.Gray, got %T", img)
+			}
+			if tt.opts != nil && (img.Bounds().Dx() < tt.opts.Width || img.Bounds().Dy() < tt.opts.Height) {
+				t.Errorf("image %v is smaller than requested %dx%d", img.Bounds(), tt.opts.Width, tt.opts.Height)
+			}
+
+			result, err := zx.DecodeImage(ctx, img, &DecodeOptions{TryHarder: true})
+			if err != nil {
+				t.Fatalf("failed to decode generated barcode: %v", err)
+			}
+			if result.Text != tt.text {
+				t.Errorf("decoded %q, want %q", result.Text, tt.text)
+			}
+			want := FormatQRCode
+			if tt.opts != nil {
+				want = tt.opts.Format
+			}
+			if result.Format != want {
+				t.Errorf("decoded format %s, want %s", result.Format, want)
+			}
+		})
+	}
+}
+
+func TestEncodeParams(t *testing.T) {
+	if _, _, err := encodeParams(&EncodeOptions{Format: FormatQRCode | FormatAztec}); err == nil {
+		t.Error("expected an error for multiple formats")
+	}
+	if _, _, err := encodeParams(&EncodeOptions{Format: FormatQRCode, ErrorCorrectionLevel: "X"}); err == nil {
+		t.Error("expected an error for an invalid QR EC level")
+	}
+	format, ecLevel, err := encodeParams(&EncodeOptions{ErrorCorrectionLevel: " q "})
+	if err != nil || format != FormatQRCode || ecLevel != "Q" {
+		t.Errorf("encodeParams = %s, %q, %v", format, ecLevel, err)
+	}
+}
+
+func TestRenderSymbol(t *testing.T) {
+	// 2x2 checkerboard
+	modules := []byte{1, 0, 0, 1}
+
+	img := renderSymbol(modules, 2, 2, FormatQRCode, &EncodeOptions{Width: 100, Height: 100, Margin: 10})
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 100 {
+		t.Fatalf("unexpected size %v", img.Bounds())
+	}
+	// 模块尺寸为 (100-20)/2 = 40，符号居中
+	if img.GrayAt(10, 10).Y != 0 || img.GrayAt(49, 49).Y != 0 {
+		t.Error("expected the top-left module to be dark")
+	}
+	if img.GrayAt(50, 10).Y != 0xFF || img.GrayAt(5, 5).Y != 0xFF {
+		t.Error("expected light module and quiet zone")
+	}
+
+	// 线性条码的条高度填满请求的高度
+	bars := renderSymbol([]byte{1, 0, 1}, 3, 1, FormatCode128, &EncodeOptions{Width: 30, Height: 40, Margin: -1})
+	if bars.Bounds().Dx() != 30 || bars.Bounds().Dy() != 40 {
+		t.Fatalf("unexpected linear size %v", bars.Bounds())
+	}
+	if bars.GrayAt(0, 0).Y != 0 || bars.GrayAt(0, 39).Y != 0 || bars.GrayAt(10, 20).Y != 0xFF {
+		t.Error("unexpected linear rendering")
+	}
+}
+
+func TestDecodePureImage(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	img, err := zx.EncodeText(ctx, "pure pipeline", &EncodeOptions{Format: FormatQRCode, Width: 200, Height: 200})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	opts := &DecodeOptions{
+		PossibleFormats: FormatQRCode,
+		Binarizer:       BinarizerFixedThreshold,
+		IsPure:          true,
+	}
+	result, err := zx.DecodeImage(ctx, img, opts)
+	if err != nil {
+		t.Fatalf("failed to decode pure image: %v", err)
+	}
+	if result.Text != "pure pipeline" {
+		t.Errorf("decoded %q", result.Text)
+	}
+
+	opts.DownscaleFactor = 5
+	if _, err := zx.DecodeImage(ctx, img, opts); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for downscale factor 5, got %v", err)
+	}
+}
+
+func TestDecodeOptionDefaults(t *testing.T) {
+	off, on := new(bool), new(bool)
+	*on = true
+
+	tests := []struct {
+		name              string
+		opts              *DecodeOptions
+		rotate, downscale bool
+	}{
+		{"TryHarder only", &DecodeOptions{TryHarder: true}, true, true},
+		{"zero value", &DecodeOptions{}, true, true},
+		{"defaults", DefaultDecodeOptions(), true, true},
+		{"explicit on", &DecodeOptions{TryRotate: on, TryDownscale: on}, true, true},
+		{"rotate off", &DecodeOptions{TryRotate: off}, false, true},
+		{"downscale off", &DecodeOptions{TryDownscale: off}, true, false},
+	}
+	for _, tt := range tests {
+		if got := tryRotate(tt.opts); got != tt.rotate {
+			t.Errorf("%s: tryRotate = %v, want %v", tt.name, got, tt.rotate)
+		}
+		if got := tryDownscale(tt.opts); got != tt.downscale {
+			t.Errorf("%s: tryDownscale = %v, want %v", tt.name, got, tt.downscale)
+		}
+	}
+}
+
+func TestValidateDecodeOptions(t *testing.T) {
+	valid := []*DecodeOptions{
+		nil,
+		DefaultDecodeOptions(),
+		{Binarizer: BinarizerBoolCast, DownscaleFactor: 2, DownscaleThreshold: 800, MinLineCount: 4, MaxSymbols: 10},
+		{CharacterSet: "shift-jis"},
+		{Symbology: &SymbologyOptions{ITFMinLength: 14, ITFMaxLength: 14}},
+	}
+	for _, opts := range valid {
+		if err := validateDecodeOptions(opts); err != nil {
+			t.Errorf("validateDecodeOptions(%+v) = %v", opts, err)
+		}
+	}
+
+	invalid := []*DecodeOptions{
+		{Binarizer: Binarizer(4)},
+		{Binarizer: Binarizer(-1)},
+		{DownscaleFactor: 1},
+		{DownscaleFactor: 5},
+		{MinLineCount: -1},
+		{DownscaleThreshold: -1},
+		{TextMode: TextMode(5)},
+		{CharacterSet: "KOI8-R"},
+		{EANAddOn: EANAddOn(3)},
+		{Symbology: &SymbologyOptions{ITFMinLength: -1}},
+		{Symbology: &SymbologyOptions{ITFMinLength: 14, ITFMaxLength: 6}},
+	}
+	for _, opts := range invalid {
+		if err := validateDecodeOptions(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestParseBinarizer(t *testing.T) {
+	tests := map[string]Binarizer{
+		"LocalAverage":     BinarizerLocalAverage,
+		"global-histogram": BinarizerGlobalHistogram,
+		"FIXED_THRESHOLD":  BinarizerFixedThreshold,
+		"boolcast":         BinarizerBoolCast,
+	}
+	for name, want := range tests {
+		got, err := ParseBinarizer(name)
+		if err != nil || got != want {
+			t.Errorf("ParseBinarizer(%q) = %v, %v; want %v", name, got, err, want)
+		}
+		if back, _ := ParseBinarizer(got.String()); back != got {
+			t.Errorf("String round trip failed for %v", got)
+		}
+	}
+	if _, err := ParseBinarizer("otsu"); err == nil {
+		t.Error("expected an error for an unknown binarizer")
+	}
+}
+
+func TestCanonicalCharacterSet(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"Shift_JIS":  "Shift_JIS",
+		"sjis":       "Shift_JIS",
+		"gb18030":    "GB18030",
+		"GBK":        "GB18030",
+		"iso8859_1":  "ISO-8859-1",
+		"Cp1252":     "windows-1252",
+		"utf-8":      "UTF-8",
+		" EUC_KR ":   "EUC-KR",
+		"UnicodeBig": "UTF-16BE",
+	}
+	for name, want := range tests {
+		got, err := canonicalCharacterSet(name)
+		if err != nil || got != want {
+			t.Errorf("canonicalCharacterSet(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"KOI8-R", "EBCDIC", "UTF-7"} {
+		if _, err := canonicalCharacterSet(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
+
+func TestParseTextMode(t *testing.T) {
+	tests := map[string]TextMode{
+		"HRI":     TextModeHRI,
+		"plain":   TextModePlain,
+		"eci":     TextModeECI,
+		"HEX":     TextModeHex,
+		"escaped": TextModeEscaped,
+	}
+	for name, want := range tests {
+		got, err := ParseTextMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseTextMode(%q) = %v, %v; want %v", name, got, err, want)
+		}
+		if back, _ := ParseTextMode(got.String()); back != got {
+			t.Errorf("String round trip failed for %v", got)
+		}
+	}
+	if _, err := ParseTextMode("base64"); err == nil {
+		t.Error("expected an error for an unknown text mode")
+	}
+}
+
+func TestDecodeTextMode(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Payload with a <GS> separator
+	const payload = "A\x1dB"
+	img, err := zx.EncodeText(ctx, payload, &EncodeOptions{Format: FormatQRCode, Width: 200, Height: 200})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	tests := []struct {
+		mode TextMode
+		want string
+	}{
+		{TextModePlain, payload},
+		{TextModeHex, "41 1D 42"},
+		{TextModeEscaped, "A<GS>B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			opts := DefaultDecodeOptions()
+			opts.TextMode = tt.mode
+			result, err := zx.DecodeImage(ctx, img, opts)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if result.Text != tt.want {
+				t.Errorf("Text = %q, want %q", result.Text, tt.want)
+			}
+			if string(result.Bytes) != payload {
+				t.Errorf("Bytes = %q, want %q", result.Bytes, payload)
+			}
+		})
+	}
+}
+
+func TestParseEANAddOn(t *testing.T) {
+	for _, want := range []EANAddOn{EANAddOnIgnore, EANAddOnRead, EANAddOnRequire} {
+		got, err := ParseEANAddOn(strings.ToLower(want.String()))
+		if err != nil || got != want {
+			t.Errorf("ParseEANAddOn(%q) = %v, %v; want %v", want.String(), got, err, want)
+		}
+	}
+	if _, err := ParseEANAddOn("optional"); err == nil {
+		t.Error("expected an error for an unknown add-on mode")
+	}
+}
+
+// ean5Modules returns the modules of an EAN-5 add-on symbol for digits.
+func ean5Modules(digits string) []byte {
+	codes := [2][10]string{
+		{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"},
+		{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"},
+	}
+	parities := []string{"GGLLL", "GLGLL", "GLLGL", "GLLLG", "LGGLL", "LLGGL", "LLLGG", "LGLGL", "LGLLG", "LLGLG"}
+
+	d := make([]int, len(digits))
+	for i, c := range digits {
+		d[i] = int(c - '0')
+	}
+	parity := parities[(3*(d[0]+d[2]+d[4])+9*(d[1]+d[3]))%10]
+
+	pattern := "1011"
+	for i, digit := range d {
+		if i > 0 {
+			pattern += "01"
+		}
+		set := 0
+		if parity[i] == 'G' {
+			set = 1
+		}
+		pattern += codes[set][digit]
+	}
+
+	modules := make([]byte, len(pattern))
+	for i, c := range pattern {
+		modules[i] = byte(c - '0')
+	}
+	return modules
+}
+
+func TestDecodeEANAddOn(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// 95 modules plus a 10 module quiet zone on each side, 3 pixels per module
+	const scale = 3
+	ean13, err := zx.EncodeText(ctx, "9781234567897", &EncodeOptions{Format: FormatEAN13, Width: 115 * scale, Height: 120})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// Place a $24.95 price supplement 9 modules to the right of the main symbol
+	addOn := ean5Modules("52495")
+	bounds := ean13.Bounds()
+	canvas := image.NewGray(image.Rect(0, 0, bounds.Dx()+(len(addOn)+9)*scale, bounds.Dy()))
+	for i := range canvas.Pix {
+		canvas.Pix[i] = 0xFF
+	}
+	draw.Draw(canvas, bounds, ean13, bounds.Min, draw.Src)
+	left := (10 + 95 + 9) * scale
+	for i, m := range addOn {
+		if m == 0 {
+			continue
+		}
+		for y := 10 * scale; y < bounds.Dy()-10*scale; y++ {
+			for x := 0; x < scale; x++ {
+				canvas.SetGray(left+i*scale+x, y, color.Gray{})
+			}
+		}
+	}
+
+	opts := DefaultDecodeOptions()
+	opts.PossibleFormats = FormatEAN13
+	result, err := zx.DecodeImage(ctx, canvas, opts)
+	if err != nil {
+		t.Fatalf("failed to decode without add-on: %v", err)
+	}
+	if result.Text != "9781234567897" || result.AddOn != "" {
+		t.Errorf("EANAddOnIgnore: Text = %q, AddOn = %q", result.Text, result.AddOn)
+	}
+
+	opts.EANAddOn = EANAddOnRead
+	result, err = zx.DecodeImage(ctx, canvas, opts)
+	if err != nil {
+		t.Fatalf("failed to decode with add-on: %v", err)
+	}
+	if result.AddOn != "52495" {
+		t.Errorf("AddOn = %q, want %q", result.AddOn, "52495")
+	}
+	if !strings.HasPrefix(result.Text, "9781234567897") {
+		t.Errorf("Text = %q", result.Text)
+	}
+
+	// Require rejects the main symbol on its own
+	opts.EANAddOn = EANAddOnRequire
+	if _, err := zx.DecodeImage(ctx, ean13, opts); !errors.Is(err, ErrNotFound) {
+		t.Errorf("EANAddOnRequire without add-on: expected ErrNotFound, got %v", err)
+	}
+}
+
+// code39CheckChar returns the mod 43 check character for Code 39 text.
+func code39CheckChar(text string) string {
+	const charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%"
+	sum := 0
+	for _, c := range text {
+		sum += strings.IndexRune(charset, c)
+	}
+	return string(charset[sum%43])
+}
+
+func TestSymbologyOptions(t *testing.T) {
+	skipJSBackend(t)
+
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	encode := func(t *testing.T, text string, format Format) image.Image {
+		t.Helper()
+		img, err := zx.EncodeText(ctx, text, &EncodeOptions{Format: format, Width: 500, Height: 120})
+		if err != nil {
+			t.Fatalf("failed to encode %q: %v", text, err)
+		}
+		return img
+	}
+	decode := func(img image.Image, format Format, sym *SymbologyOptions) (*Result, error) {
+		opts := DefaultDecodeOptions()
+		opts.PossibleFormats = format
+		opts.Symbology = sym
+		return zx.DecodeImage(ctx, img, opts)
+	}
+	expectText := func(t *testing.T, img image.Image, format Format, sym *SymbologyOptions, want string) {
+		t.Helper()
+		result, err := decode(img, format, sym)
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if result.Text != want {
+			t.Errorf("Text = %q, want %q", result.Text, want)
+		}
+	}
+	expectNotFound := func(t *testing.T, img image.Image, format Format, sym *SymbologyOptions) {
+		t.Helper()
+		if _, err := decode(img, format, sym); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+
+	t.Run("Code39ExtendedMode", func(t *testing.T) {
+		img := encode(t, "+A+B-1", FormatCode39)
+		expectText(t, img, FormatCode39, nil, "ab-1")
+		sym := DefaultSymbologyOptions()
+		sym.Code39ExtendedMode = new(bool)
+		expectText(t, img, FormatCode39, sym, "+A+B-1")
+	})
+
+	t.Run("Code39CheckDigit", func(t *testing.T) {
+		sym := DefaultSymbologyOptions()
+		sym.Code39CheckDigit = true
+		withCheck := "CODE39" + code39CheckChar("CODE39")
+		expectText(t, encode(t, withCheck, FormatCode39), FormatCode39, sym, withCheck)
+		expectNotFound(t, encode(t, "CODE39", FormatCode39), FormatCode39, sym)
+	})
+
+	t.Run("ITFCheckDigit", func(t *testing.T) {
+		sym := DefaultSymbologyOptions()
+		sym.ITFCheckDigit = true
+		expectText(t, encode(t, "12345678901231", FormatITF), FormatITF, sym, "12345678901231")
+		expectNotFound(t, encode(t, "12345678901234", FormatITF), FormatITF, sym)
+	})
+
+	t.Run("RejectedSymbolDoesNotHideAccepted", func(t *testing.T) {
+		// The rejected symbol sits in the middle row band, where the 1D reader
+		// starts scanning, so it is found before the accepted one above it.
+		accepted := encode(t, "12345678901231", FormatITF)
+		rejected := encode(t, "12345678901234", FormatITF)
+		b := accepted.Bounds()
+		img := image.NewGray(image.Rect(0, 0, b.Dx(), 3*b.Dy()))
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(img, b, accepted, b.Min, draw.Src)
+		draw.Draw(img, b.Add(image.Pt(0, b.Dy())), rejected, rejected.Bounds().Min, draw.Src)
+
+		sym := DefaultSymbologyOptions()
+		sym.ITFCheckDigit = true
+		expectText(t, img, FormatITF, sym, "12345678901231")
+	})
+
+	t.Run("ITFLength", func(t *testing.T) {
+		img := encode(t, "12345678901231", FormatITF)
+		expectText(t, img, FormatITF, &SymbologyOptions{ITFMinLength: 14, ITFMaxLength: 14}, "12345678901231")
+		expectNotFound(t, img, FormatITF, &SymbologyOptions{ITFMinLength: 6, ITFMaxLength: 12})
+		expectNotFound(t, encode(t, "123456", FormatITF), FormatITF, &SymbologyOptions{ITFMinLength: 14})
+	})
+
+	t.Run("CodabarStartStop", func(t *testing.T) {
+		img := encode(t, "A40156B", FormatCodabar)
+		expectText(t, img, FormatCodabar, nil, "A40156B")
+		sym := DefaultSymbologyOptions()
+		sym.CodabarStartStop = new(bool)
+		expectText(t, img, FormatCodabar, sym, "40156")
+	})
+
+	t.Run("PartialOptionsKeepDefaults", func(t *testing.T) {
+		// Setting only an unrelated field leaves Full ASCII mode and start/stop characters on
+		sym := &SymbologyOptions{Code39CheckDigit: true}
+		expectText(t, encode(t, "+A"+code39CheckChar("+A"), FormatCode39), FormatCode39, sym, "a"+code39CheckChar("+A"))
+		expectText(t, encode(t, "A40156B", FormatCodabar), FormatCodabar, sym, "A40156B")
+	})
+
+	t.Run("InvalidITFLength", func(t *testing.T) {
+		img := encode(t, "12345678901231", FormatITF)
+		if _, err := decode(img, FormatITF, &SymbologyOptions{ITFMinLength: 14, ITFMaxLength: 10}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	})
+}
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{"not found", context.Background(), &nativeError{code: codeNotFound, msg: "No barcode found"}, ErrNotFound},
+		{"checksum", context.Background(), &nativeError{code: codeChecksum, msg: "Checksum error"}, ErrChecksum},
+		{"format", context.Background(), &nativeError{code: codeFormat, msg: "Format error"}, ErrFormat},
+		{"unsupported", context.Background(), &nativeError{code: codeUnsupported, msg: "Unsupported"}, ErrUnsupportedFormat},
+		{"invalid input", context.Background(), fmt.Errorf("wrapped: %w", &nativeError{code: codeInvalidInput, msg: "Invalid"}), ErrInvalidInput},
+		{"deadline", context.Background(), context.DeadlineExceeded, ErrCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.ctx, BackendCGO, "decode", tt.err)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("underlying error %v is not reachable from %v", tt.err, err)
+			}
+			var zerr *Error
+			if !errors.As(err, &zerr) || zerr.Backend != BackendCGO || zerr.Op != "decode" {
+				t.Errorf("errors.As(*Error) failed for %#v", err)
+			}
+		})
+	}
+
+	// A canceled context wins over the backend's own error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := wrapError(ctx, BackendWASM, "decode", &nativeError{code: codeInternal, msg: "trap"})
+	if !errors.Is(err, ErrCanceled) || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+
+	// Internal errors are wrapped but not classified
+	err = wrapError(context.Background(), BackendCGO, "encode", &nativeError{code: codeInternal, msg: "out of memory"})
+	for _, kind := range []error{ErrNotFound, ErrChecksum, ErrFormat, ErrInvalidInput, ErrCanceled} {
+		if errors.Is(err, kind) {
+			t.Errorf("internal error unexpectedly matches %v", kind)
+		}
+	}
+	if err.Error() != "zxing cgo encode: out of memory" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	// Already classified errors pass through unchanged
+	orig := newError(BackendWASM, "decode", ErrRuntimeClosed, nil)
+	if got := wrapError(context.Background(), BackendCGO, "encode", orig); got != orig {
+		t.Errorf("wrapError rewrapped %v as %v", orig, got)
+	}
+	if orig.Error() != "zxing wasm decode: runtime closed" {
+		t.Errorf("unexpected message %q", orig.Error())
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	zx := newTestZXing(t)
+
+	ctx := context.Background()
+	if _, err := zx.DecodeBytes(ctx, nil, 10, 10, nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty data: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := zx.DecodeBytes(ctx, make([]byte, 16), 10, 10, nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("short data: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := zx.EncodeText(ctx, "", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty text: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := zx.EncodeText(ctx, "not digits", &EncodeOptions{Format: FormatEAN13}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("invalid EAN-13 content: expected ErrInvalidInput, got %v", err)
+	}
+
+	// A blank image contains no barcode
+	blank := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range blank.Pix {
+		blank.Pix[i] = 0xFF
+	}
+	if _, err := zx.DecodeImage(ctx, blank, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("blank image: expected ErrNotFound, got %v", err)
+	}
+	if _, err := zx.DecodeImage(ctx, blank, &DecodeOptions{CharacterSet: "KOI8-R"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unsupported character set: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := zx.DecodeImage(ctx, blank, &DecodeOptions{CharacterSet: "Shift_JIS"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("blank image with character set: expected ErrNotFound, got %v", err)
+	}
+
+	// The backend that is not compiled into this build reports ErrBackendUnavailable
+	config := DefaultConfig()
+	var other ZXing = &cgoZXing{config: config}
+	if cgoAvailable {
+		other = &wasmZXing{config: config}
+	}
+	if _, err := other.DecodeImage(ctx, blank, nil); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("unavailable backend: expected ErrBackendUnavailable, got %v", err)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	config := DefaultConfig()
+
+	if config.Backend != BackendAuto {
+		t.Errorf("expected default backend %s, got %s", BackendAuto, config.Backend)
+	}
+
+	if config.Timeout != 30 {
+		t.Errorf("expected default timeout 30, got %d", config.Timeout)
+	}
+
+	if config.Debug != false {
+		t.Errorf("expected default debug false, got %t", config.Debug)
+	}
+
+	if config.WASMPath != "wasm/zxingwrapper.wasm" {
+		t.Errorf("expected default WASM path wasm/zxingwrapper.wasm, got %s", config.WASMPath)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), &Config{Timeout: 5})
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 5*time.Second {
+		t.Errorf("expected a deadline within 5s, got %v (set=%v)", deadline, ok)
+	}
+
+	// An existing deadline wins over Config.Timeout.
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Hour)
+	defer parentCancel()
+	ctx, cancel = withTimeout(parent, &Config{Timeout: 5})
+	defer cancel()
+	if ctx != parent {
+		t.Error("expected the caller's deadline to be kept")
+	}
+
+	ctx, cancel = withTimeout(context.Background(), &Config{})
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline for Timeout 0")
+	}
+}
+
+func TestRunWithContext(t *testing.T) {
+	v, err := runWithContext(context.Background(), func() (int, error) { return 42, nil })
+	if v != 42 || err != nil {
+		t.Errorf("got %d, %v", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	start := time.Now()
+	_, err = runWithContext(ctx, func() (int, error) {
+		defer close(finished)
+		<-release
+		return 1, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v, want promptly after the deadline", elapsed)
+	}
+
+	// The abandoned worker can still finish without blocking.
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("abandoned worker did not finish")
+	}
+}
+
+func TestRunLimited(t *testing.T) {
+	slots := make(chan struct{}, 2)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runLimited(context.Background(), slots, func() (int, error) {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				return 0, nil
+			})
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d calls ran at once, want at most 2", p)
+	}
+
+	// A caller waiting for a slot gives up when its context ends, and the slot
+	// of an abandoned call is only freed once the call returns.
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	for i := 0; i < cap(slots); i++ {
+		go runLimited(context.Background(), slots, func() (int, error) {
+			<-release
+			return 0, nil
+		})
+	}
+	for len(slots) < cap(slots) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := runLimited(ctx, slots, func() (int, error) { return 1, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded while all slots are taken, got %v", err)
+	}
+	close(release)
+	if v, err := runLimited(context.Background(), slots, func() (int, error) { return 1, nil }); v != 1 || err != nil {
+		t.Errorf("got %d, %v after the slots were released", v, err)
+	}
+}
+
+// TestConcurrentDecodeErrors decodes valid and invalid images in parallel and
+// checks that every call reports its own outcome. Run it with -race to also
+// check the native bindings.
+func TestConcurrentDecodeErrors(t *testing.T) {
+	config := DefaultConfig()
+	config.WASMPath = testWASMPath
+	config.Concurrency = 4
+	zx := newTestZXingConfig(t, config)
+
+	valid := loadTestImage(t, "qrcode_www.bing.com.png")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	want, err := zx.DecodeImage(ctx, valid, nil)
+	if err != nil {
+		t.Fatalf("failed to decode QR code: %v", err)
+	}
+
+	blank := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range blank.Pix {
+		blank.Pix[i] = 0xFF
+	}
+
+	const workers, rounds = 8, 10
+	errs := make(chan error, workers*rounds)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if (w+i)%2 == 0 {
+					result, err := zx.DecodeImage(ctx, valid, nil)
+					switch {
+					case err != nil:
+						errs <- fmt.Errorf("valid image: %w", err)
+					case result.Text != want.Text:
+						errs <- fmt.Errorf("valid image: got %q, want %q", result.Text, want.Text)
+					}
+					continue
+				}
+				_, err := zx.DecodeImage(ctx, blank, nil)
+				switch {
+				case !errors.Is(err, ErrNotFound):
+					errs <- fmt.Errorf("blank image: expected ErrNotFound, got %v", err)
+				case !strings.HasSuffix(err.Error(), "No barcode found"):
+					errs <- fmt.Errorf("blank image: unexpected message %q", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDecodeHonorsDeadline(t *testing.T) {
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	_, err := zx.DecodeImage(ctx, testPattern(64, 64), nil)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ErrCanceled wrapping DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEncodeHonorsCanceledContext(t *testing.T) {
+	zx := newTestZXing(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := zx.EncodeText(ctx, "canceled", nil); !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("EncodeText: expected ErrCanceled wrapping context.Canceled, got %v", err)
+	}
+	if _, _, _, err := zx.EncodeToBytes(ctx, "canceled", nil); !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("EncodeToBytes: expected ErrCanceled wrapping context.Canceled, got %v", err)
+	}
+}
+
+// benchmarkImage returns a 12 MP RGBA photo-sized test image.
+func benchmarkImage() *image.RGBA {
+	return testPattern(4000, 3000)
+}
+
+func BenchmarkImageToGray(b *testing.B) {
+	src := benchmarkImage()
+	gray := image.NewGray(src.Bounds())
+	draw.Draw(gray, gray.Bounds(), src, image.Point{}, draw.Src)
+	ycbcr := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio420)
+
+	for _, bc := range []struct {
+		name string
+		img  image.Image
+	}{
+		{"Gray", gray},
+		{"YCbCr", ycbcr},
+		{"RGBA", src},
+		{"Generic", opaqueImage{src}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				imageToGray(bc.img)
+			}
+		})
+	}
+}
+
+func BenchmarkImageToRGBA(b *testing.B) {
+	src := benchmarkImage()
+	b.Run("RGBA", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			imageToRGBA(src)
+		}
+	})
+	b.Run("Generic", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			imageToRGBA(opaqueImage{src})
+		}
+	})
 }
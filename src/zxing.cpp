@@ -1,12 +1,24 @@
 #include "zxing.h"
 #include "zxing_internal.h"
 #include "ReadBarcode.h"
+#include "WriteBarcode.h"
+#include "Version.h"
+#include "GTIN.h"
 #include <memory>
 #include <string>
 #include <vector>
 #include <cstdarg>
 #include <cstring>
 #include <cstdlib>
+#include <algorithm>
+#include <array>
+#include <stdexcept>
+
+// 编码使用 zxing-cpp 3.0 引入的 CreateBarcodeFromText/WriteBarcodeToImage，
+// 构建工具会检出 cmd/build 中固定的 zxing-cpp 版本（v3.0.2）
+#if ZXING_VERSION_MAJOR < 3
+#error "src/zxing.cpp requires zxing-cpp 3.x; run 'go run ./cmd/build build-lib' to check out the pinned version"
+#endif
 
 #ifdef __EMSCRIPTEN__
 #include <emscripten.h>
@@ -21,15 +33,29 @@
 
 using namespace ZXing;
 
-// 错误信息缓冲区
-static char last_error[256] = {0};
+// 当前线程最近一次错误，仅供 get_last_error 读取；并发调用方应使用各函数的 error 参数
+static thread_local char last_error[sizeof(ZXingError::message)] = {0};
+static thread_local int last_error_code = ZXING_ERROR_NONE;
 
-// 设置错误信息
-static void set_error(const char* format, ...) {
+// 重置调用方的错误信息，error 可为 NULL
+static void clear_error(ZXingError* error) {
+    if (error) {
+        error->code = ZXING_ERROR_NONE;
+        error->message[0] = '\0';
+    }
+}
+
+// 设置错误码和错误信息：写入调用方的 error（可为 NULL）和当前线程的最近错误
+static void set_error(ZXingError* error, int code, const char* format, ...) {
+    last_error_code = code;
     va_list args;
     va_start(args, format);
     vsnprintf(last_error, sizeof(last_error), format, args);
     va_end(args);
+    if (error) {
+        error->code = code;
+        memcpy(error->message, last_error, sizeof(error->message));
+    }
 }
 
 // 初始化标志
@@ -83,16 +109,368 @@ static ::BarcodeFormat convert_format(ZXing::BarcodeFormat format) {
         case ZXing::BarcodeFormat::PDF417: return FORMAT_PDF_417;
         case ZXing::BarcodeFormat::UPCA: return FORMAT_UPC_A;
         case ZXing::BarcodeFormat::UPCE: return FORMAT_UPC_E;
-        case ZXing::BarcodeFormat::DataBar: return FORMAT_CODABAR; // Map DataBar to Codabar (no direct C equivalent)
-        case ZXing::BarcodeFormat::DataBarExp: return FORMAT_CODABAR; // Map DataBarExp to Codabar
-        case ZXing::BarcodeFormat::DataBarLtd: return FORMAT_CODABAR; // Map DataBarLtd to Codabar
-        case ZXing::BarcodeFormat::MicroQRCode: return FORMAT_QR_CODE; // 使用QRCode作为替代
-        case ZXing::BarcodeFormat::RMQRCode: return FORMAT_QR_CODE; // 使用QRCode作为替代
-        case ZXing::BarcodeFormat::DXFilmEdge: return FORMAT_CODE_128; // 使用Code128作为替代
+        case ZXing::BarcodeFormat::DataBar: return FORMAT_DATABAR;
+        case ZXing::BarcodeFormat::DataBarExp: return FORMAT_DATABAR_EXPANDED;
+        case ZXing::BarcodeFormat::DataBarLtd: return FORMAT_DATABAR_LIMITED;
+        case ZXing::BarcodeFormat::MicroQRCode: return FORMAT_MICRO_QR_CODE;
+        case ZXing::BarcodeFormat::RMQRCode: return FORMAT_RMQR_CODE;
+        case ZXing::BarcodeFormat::DXFilmEdge: return FORMAT_DX_FILM_EDGE;
         default: return FORMAT_NONE;
     }
 }
 
+// 转换 C 格式到 ZXing 格式，仅接受单个格式，无法识别时返回 BarcodeFormat::None
+static ZXing::BarcodeFormat to_zxing_format(int format) {
+    switch (format) {
+        case FORMAT_QR_CODE: return ZXing::BarcodeFormat::QRCode;
+        case FORMAT_AZTEC: return ZXing::BarcodeFormat::Aztec;
+        case FORMAT_CODABAR: return ZXing::BarcodeFormat::Codabar;
+        case FORMAT_CODE_39: return ZXing::BarcodeFormat::Code39;
+        case FORMAT_CODE_93: return ZXing::BarcodeFormat::Code93;
+        case FORMAT_CODE_128: return ZXing::BarcodeFormat::Code128;
+        case FORMAT_DATA_MATRIX: return ZXing::BarcodeFormat::DataMatrix;
+        case FORMAT_EAN_8: return ZXing::BarcodeFormat::EAN8;
+        case FORMAT_EAN_13: return ZXing::BarcodeFormat::EAN13;
+        case FORMAT_ITF: return ZXing::BarcodeFormat::ITF;
+        case FORMAT_MAXICODE: return ZXing::BarcodeFormat::MaxiCode;
+        case FORMAT_PDF_417: return ZXing::BarcodeFormat::PDF417;
+        case FORMAT_UPC_A: return ZXing::BarcodeFormat::UPCA;
+        case FORMAT_UPC_E: return ZXing::BarcodeFormat::UPCE;
+        case FORMAT_DATABAR: return ZXing::BarcodeFormat::DataBar;
+        case FORMAT_DATABAR_EXPANDED: return ZXing::BarcodeFormat::DataBarExp;
+        case FORMAT_DATABAR_LIMITED: return ZXing::BarcodeFormat::DataBarLtd;
+        case FORMAT_MICRO_QR_CODE: return ZXing::BarcodeFormat::MicroQRCode;
+        case FORMAT_RMQR_CODE: return ZXing::BarcodeFormat::RMQRCode;
+        case FORMAT_DX_FILM_EDGE: return ZXing::BarcodeFormat::DXFilmEdge;
+        default: return ZXing::BarcodeFormat::None;
+    }
+}
+
+// Maps a channel count to the matching ZXing image format, or ImageFormat::None.
+static ImageFormat image_format_from_channels(int channels) {
+    auto formats = std::array{ImageFormat::None, ImageFormat::Lum, ImageFormat::LumA,
+                              ImageFormat::RGB, ImageFormat::RGBA};
+    if (channels < 1 || channels >= static_cast<int>(formats.size())) {
+        return ImageFormat::None;
+    }
+    return formats.at(channels);
+}
+
+// Maps a TextModeType to the ZXing text mode; unknown values fall back to HRI.
+static TextMode to_text_mode(int text_mode) {
+    switch (text_mode) {
+        case TEXT_MODE_PLAIN: return TextMode::Plain;
+        case TEXT_MODE_ECI: return TextMode::ECI;
+        case TEXT_MODE_HEX: return TextMode::Hex;
+        case TEXT_MODE_ESCAPED: return TextMode::Escaped;
+        default: return TextMode::HRI;
+    }
+}
+
+// Builds ZXing reader options from the C decode options.
+static ReaderOptions make_reader_options(const DecodeOptions* options) {
+    ReaderOptions reader_opts;
+    if (!options) {
+        return reader_opts;
+    }
+    reader_opts.setTryHarder(options->try_harder != 0);
+    reader_opts.setTryRotate(options->try_rotate != 0);
+    reader_opts.setTryInvert(options->try_invert != 0);
+    reader_opts.setTryDownscale(options->try_downscale != 0);
+    reader_opts.setBinarizer(static_cast<Binarizer>(options->binarizer));
+    reader_opts.setIsPure(options->is_pure != 0);
+    reader_opts.setReturnErrors(options->return_errors != 0);
+    reader_opts.setTextMode(to_text_mode(options->text_mode));
+    if (options->ean_add_on >= EAN_ADD_ON_IGNORE && options->ean_add_on <= EAN_ADD_ON_REQUIRE) {
+        reader_opts.setEanAddOnSymbol(static_cast<EanAddOnSymbol>(options->ean_add_on));
+    }
+    if (options->character_set > 0 && options->character_set < static_cast<int>(CharacterSet::CharsetCount)) {
+        reader_opts.setCharacterSet(static_cast<CharacterSet>(options->character_set));
+    }
+    // Zero keeps the library default for the numeric tuning fields
+    if (options->downscale_threshold > 0) {
+        reader_opts.setDownscaleThreshold(static_cast<uint16_t>(std::min(options->downscale_threshold, 0xFFFF)));
+    }
+    if (options->downscale_factor > 0) {
+        reader_opts.setDownscaleFactor(static_cast<uint8_t>(options->downscale_factor));
+    }
+    if (options->min_line_count > 0) {
+        reader_opts.setMinLineCount(static_cast<uint8_t>(std::min(options->min_line_count, 0xFF)));
+    }
+    if (options->max_number_of_symbols > 0) {
+        reader_opts.setMaxNumberOfSymbols(static_cast<uint8_t>(std::min(options->max_number_of_symbols, 0xFF)));
+    }
+
+    std::vector<ZXing::BarcodeFormat> selected_formats;
+    if (options->formats != FORMAT_ALL && options->formats != FORMAT_NONE) {
+        if (options->formats & FORMAT_QR_CODE) selected_formats.push_back(ZXing::BarcodeFormat::QRCode);
+        if (options->formats & FORMAT_AZTEC) selected_formats.push_back(ZXing::BarcodeFormat::Aztec);
+        if (options->formats & FORMAT_CODABAR) selected_formats.push_back(ZXing::BarcodeFormat::Codabar);
+        if (options->formats & FORMAT_CODE_39) selected_formats.push_back(ZXing::BarcodeFormat::Code39);
+        if (options->formats & FORMAT_CODE_93) selected_formats.push_back(ZXing::BarcodeFormat::Code93);
+        if (options->formats & FORMAT_CODE_128) selected_formats.push_back(ZXing::BarcodeFormat::Code128);
+        if (options->formats & FORMAT_DATA_MATRIX) selected_formats.push_back(ZXing::BarcodeFormat::DataMatrix);
+        if (options->formats & FORMAT_EAN_8) selected_formats.push_back(ZXing::BarcodeFormat::EAN8);
+        if (options->formats & FORMAT_EAN_13) selected_formats.push_back(ZXing::BarcodeFormat::EAN13);
+        if (options->formats & FORMAT_ITF) selected_formats.push_back(ZXing::BarcodeFormat::ITF);
+        if (options->formats & FORMAT_MAXICODE) selected_formats.push_back(ZXing::BarcodeFormat::MaxiCode);
+        if (options->formats & FORMAT_PDF_417) selected_formats.push_back(ZXing::BarcodeFormat::PDF417);
+        if (options->formats & FORMAT_UPC_A) selected_formats.push_back(ZXing::BarcodeFormat::UPCA);
+        if (options->formats & FORMAT_UPC_E) selected_formats.push_back(ZXing::BarcodeFormat::UPCE);
+        if (options->formats & FORMAT_DATABAR) selected_formats.push_back(ZXing::BarcodeFormat::DataBar);
+        if (options->formats & FORMAT_DATABAR_EXPANDED) selected_formats.push_back(ZXing::BarcodeFormat::DataBarExp);
+        if (options->formats & FORMAT_DATABAR_LIMITED) selected_formats.push_back(ZXing::BarcodeFormat::DataBarLtd);
+        if (options->formats & FORMAT_MICRO_QR_CODE) selected_formats.push_back(ZXing::BarcodeFormat::MicroQRCode);
+        if (options->formats & FORMAT_RMQR_CODE) selected_formats.push_back(ZXing::BarcodeFormat::RMQRCode);
+        if (options->formats & FORMAT_DX_FILM_EDGE) selected_formats.push_back(ZXing::BarcodeFormat::DXFilmEdge);
+        reader_opts.setFormats(ZXing::BarcodeFormats(std::move(selected_formats)));
+    }
+    return reader_opts;
+}
+
+// Copies size bytes into a malloc'd, NUL-terminated buffer so that binary
+// payloads with embedded zero bytes survive the trip across the C ABI.
+static unsigned char* copy_bytes(const void* data, size_t size) {
+    auto* buf = static_cast<unsigned char*>(malloc(size + 1));
+    if (!buf) {
+        return nullptr;
+    }
+    if (size > 0) {
+        memcpy(buf, data, size);
+    }
+    buf[size] = 0;
+    return buf;
+}
+
+// Maps a ZXing decode error to its ZXingErrorCode.
+static int error_code(const Error& error) {
+    switch (error.type()) {
+        case Error::Checksum: return ZXING_ERROR_CHECKSUM;
+        case Error::Format: return ZXING_ERROR_FORMAT;
+        case Error::Unsupported: return ZXING_ERROR_UNSUPPORTED;
+        default: return ZXING_ERROR_NONE;
+    }
+}
+
+// Returns the modifier character of the symbology identifier ("]A1" -> '1'), or 0.
+static char symbology_modifier(const Barcode& barcode) {
+    const std::string id = barcode.symbologyIdentifier();
+    return id.size() >= 3 ? id[2] : 0;
+}
+
+// Applies the Code 39 and ITF check digit and length requirements.
+// zxing-cpp reports optional check digits through the symbology identifier:
+// Code 39 has a valid check digit iff the modifier is odd, ITF iff it is '1'.
+static bool symbology_accepts(const Barcode& barcode, const DecodeOptions* options) {
+    if (!options) {
+        return true;
+    }
+    const char modifier = symbology_modifier(barcode);
+    if (barcode.format() == ZXing::BarcodeFormat::Code39 && options->code39_check_digit) {
+        return modifier >= '0' && modifier <= '9' && (modifier - '0') % 2 == 1;
+    }
+    if (barcode.format() == ZXing::BarcodeFormat::ITF) {
+        if (options->itf_check_digit && modifier != '1') {
+            return false;
+        }
+        const int length = static_cast<int>(barcode.bytes().size());
+        if (options->itf_min_length > 0 && length < options->itf_min_length) {
+            return false;
+        }
+        if (options->itf_max_length > 0 && length > options->itf_max_length) {
+            return false;
+        }
+    }
+    return true;
+}
+
+// Reports whether a barcode should be returned to the caller: valid symbols
+// always are, symbols that failed to decode only when return_errors is set.
+// Symbols rejected by the symbology options are never returned.
+static bool accept_barcode(const Barcode& barcode, const DecodeOptions* options) {
+    return (barcode.isValid() || (options && options->return_errors && barcode.error())) &&
+           symbology_accepts(barcode, options);
+}
+
+// Removes the barcodes rejected by the symbology options.
+static void filter_barcodes(Barcodes& barcodes, const DecodeOptions* options) {
+    barcodes.erase(std::remove_if(barcodes.begin(), barcodes.end(),
+                                  [options](const Barcode& b) { return !symbology_accepts(b, options); }),
+                   barcodes.end());
+}
+
+// Reports whether a single decode must look past the first symbol found: the
+// check digit and length filters may reject it, and with return_errors a symbol
+// that failed to decode must not hide a valid one.
+static bool needs_candidates(const DecodeOptions* options) {
+    return options && (options->code39_check_digit || options->itf_check_digit || options->itf_min_length > 0 ||
+                       options->itf_max_length > 0 || options->return_errors);
+}
+
+// Returns the first symbol in image that accept_barcode keeps, so a symbol
+// rejected by the symbology options does not hide a valid one. The reader stops
+// at the first symbol unless needs_candidates says later ones may be kept instead.
+// When none is kept, returns the first located symbol that failed to decode, for
+// set_decode_failure to report, or an empty Barcode when there is none.
+static Barcode read_first_accepted(const ImageView& image, const DecodeOptions* options) {
+    ReaderOptions reader_opts = make_reader_options(options);
+    if (!needs_candidates(options)) {
+        reader_opts.setMaxNumberOfSymbols(1);
+    }
+    Barcodes barcodes = ReadBarcodes(image, reader_opts);
+    for (const auto& barcode : barcodes) {
+        if (accept_barcode(barcode, options)) {
+            return barcode;
+        }
+    }
+    for (const auto& barcode : barcodes) {
+        if (barcode.error() && symbology_accepts(barcode, options)) {
+            return barcode;
+        }
+    }
+    return Barcode();
+}
+
+// Re-encodes Full ASCII text as plain Code 39 characters, undoing the extended
+// mode interpretation applied by zxing-cpp.
+static std::string code39_full_ascii_encode(const std::string& text) {
+    std::string out;
+    for (unsigned char c : text) {
+        if (c == 0) {
+            out += "%U";
+        } else if (c <= 26) {
+            out += '$';
+            out += static_cast<char>('A' + c - 1);
+        } else if (c <= 31) {
+            out += '%';
+            out += static_cast<char>('A' + c - 27);
+        } else if (c == ' ' || c == '-' || c == '.' || (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z')) {
+            out += static_cast<char>(c);
+        } else if (c <= ',') {
+            out += '/';
+            out += static_cast<char>('A' + c - '!');
+        } else if (c == '/') {
+            out += "/O";
+        } else if (c == ':') {
+            out += "/Z";
+        } else if (c <= '?') {
+            out += '%';
+            out += static_cast<char>('F' + c - ';');
+        } else if (c == '@') {
+            out += "%V";
+        } else if (c <= '_') {
+            out += '%';
+            out += static_cast<char>('K' + c - '[');
+        } else if (c == '`') {
+            out += "%W";
+        } else if (c <= 'z') {
+            out += '+';
+            out += static_cast<char>('A' + c - 'a');
+        } else if (c <= 127) {
+            out += '%';
+            out += static_cast<char>('P' + c - '{');
+        } else {
+            out += static_cast<char>(c);
+        }
+    }
+    return out;
+}
+
+// Returns the result text with the Code 39 and Codabar presentation options
+// applied. Only the HRI and plain text modes are rewritten.
+static std::string result_text(const Barcode& barcode, const DecodeOptions* options) {
+    std::string text = barcode.text();
+    if (!options || (options->text_mode != TEXT_MODE_HRI && options->text_mode != TEXT_MODE_PLAIN)) {
+        return text;
+    }
+    // Modifiers 4-7 mark a symbol that was interpreted as Full ASCII
+    if (barcode.format() == ZXing::BarcodeFormat::Code39 && !options->code39_extended && symbology_modifier(barcode) >= '4') {
+        return code39_full_ascii_encode(text);
+    }
+    if (barcode.format() == ZXing::BarcodeFormat::Codabar && !options->codabar_start_stop && text.size() >= 2) {
+        return text.substr(1, text.size() - 2);
+    }
+    return text;
+}
+
+// Allocates a DecodeResult for a decoded barcode. Returns nullptr on allocation failure.
+static DecodeResult* new_decode_result(const Barcode& barcode, const DecodeOptions* options) {
+    auto* result = new DecodeResult();
+    const std::string text = result_text(barcode, options);
+    result->text = reinterpret_cast<char*>(copy_bytes(text.data(), text.size()));
+    if (!result->text) {
+        delete result;
+        return nullptr;
+    }
+    result->text_length = static_cast<int>(text.size());
+    result->format = convert_format(barcode.format());
+    result->confidence = 1.0f;
+
+    const auto& position = barcode.position();
+    result->x1 = position.topLeft().x;
+    result->y1 = position.topLeft().y;
+    result->x2 = position.topRight().x;
+    result->y2 = position.topRight().y;
+    result->x3 = position.bottomRight().x;
+    result->y3 = position.bottomRight().y;
+    result->x4 = position.bottomLeft().x;
+    result->y4 = position.bottomLeft().y;
+
+    result->symbology_identifier = strdup(barcode.symbologyIdentifier().c_str());
+    result->ec_level = strdup(barcode.ecLevel().c_str());
+    result->version = strdup(barcode.version().c_str());
+    result->sequence_id = strdup(barcode.sequenceId().c_str());
+    result->error_type = error_code(barcode.error());
+    result->error_message = strdup(barcode.error().msg().c_str());
+    result->ean_add_on = strdup(GTIN::EanAddOn(barcode).c_str());
+    if (!result->symbology_identifier || !result->ec_level || !result->version || !result->sequence_id ||
+        !result->error_message || !result->ean_add_on) {
+        free_result(result);
+        return nullptr;
+    }
+    result->orientation = barcode.orientation();
+    result->is_mirrored = barcode.isMirrored() ? 1 : 0;
+    result->is_inverted = barcode.isInverted() ? 1 : 0;
+    result->line_count = barcode.lineCount();
+    result->content_type = static_cast<int>(barcode.contentType());
+    result->has_eci = barcode.hasECI() ? 1 : 0;
+    result->sequence_size = barcode.sequenceSize();
+    result->sequence_index = barcode.sequenceIndex();
+
+    const ByteArray& bytes = barcode.bytes();
+    const ByteArray bytes_eci = barcode.bytesECI();
+    result->bytes = copy_bytes(bytes.data(), bytes.size());
+    result->bytes_eci = copy_bytes(bytes_eci.data(), bytes_eci.size());
+    if (!result->bytes || !result->bytes_eci) {
+        free_result(result);
+        return nullptr;
+    }
+    result->bytes_length = static_cast<int>(bytes.size());
+    result->bytes_eci_length = static_cast<int>(bytes_eci.size());
+    return result;
+}
+
+// Records why a single-symbol decode produced no valid barcode. A symbol that was
+// located but could not be decoded reports its checksum or format error instead
+// of "not found".
+static void set_decode_failure(const Barcode& barcode, ZXingError* error) {
+    const Error& failure = barcode.error();
+    switch (failure.type()) {
+        case Error::Checksum:
+            set_error(error, ZXING_ERROR_CHECKSUM, "Checksum error: %s", failure.msg().c_str());
+            break;
+        case Error::Format:
+            set_error(error, ZXING_ERROR_FORMAT, "Format error: %s", failure.msg().c_str());
+            break;
+        case Error::Unsupported:
+            set_error(error, ZXING_ERROR_UNSUPPORTED, "Unsupported: %s", failure.msg().c_str());
+            break;
+        default:
+            set_error(error, ZXING_ERROR_NOT_FOUND, "No barcode found");
+            break;
+    }
+}
+
 // 解码单个条码 - 简化实现，直接使用文件路径
 DecodeResultInternal* zxing_decode(const Image* image, int formats, int try_harder, int try_rotate, int try_invert, int try_downscale) {
     // 这个函数在Go wrapper中不会被使用
@@ -128,7 +506,7 @@ void zxing_free_results(DecodeResultInternal** results, int count) {
 EXPORT DecodeOptions* create_default_options() {
     DecodeOptions* options = new DecodeOptions();
     if (!options) {
-        set_error("Failed to allocate memory for options");
+        set_error(nullptr, ZXING_ERROR_INTERNAL, "Failed to allocate memory for options");
         return nullptr;
     }
     
@@ -137,6 +515,22 @@ EXPORT DecodeOptions* create_default_options() {
     options->try_rotate = 1;
     options->try_invert = 0;
     options->try_downscale = 1;
+    options->binarizer = BINARIZER_LOCAL_AVERAGE;
+    options->downscale_threshold = 0;
+    options->downscale_factor = 0;
+    options->is_pure = 0;
+    options->min_line_count = 0;
+    options->max_number_of_symbols = 0;
+    options->return_errors = 0;
+    options->text_mode = TEXT_MODE_HRI;
+    options->character_set = 0;
+    options->ean_add_on = EAN_ADD_ON_IGNORE;
+    options->code39_extended = 1;
+    options->code39_check_digit = 0;
+    options->itf_check_digit = 0;
+    options->itf_min_length = 0;
+    options->itf_max_length = 0;
+    options->codabar_start_stop = 1;
     
     return options;
 }
@@ -154,13 +548,117 @@ EXPORT void configure_decode_options(DecodeOptions* options, int formats, int tr
     options->try_downscale = try_downscale;
 }
 
+// Configures the reader tuning fields of an existing decode options structure.
+EXPORT int configure_decode_tuning(DecodeOptions* options, int binarizer, int downscale_threshold,
+                                   int downscale_factor, int is_pure, int min_line_count,
+                                   int max_number_of_symbols, int return_errors, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    if (binarizer < BINARIZER_LOCAL_AVERAGE || binarizer > BINARIZER_BOOL_CAST) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid binarizer: %d", binarizer);
+        return -1;
+    }
+    if (downscale_factor != 0 && (downscale_factor < 2 || downscale_factor > 4)) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid downscale factor: %d (must be 2, 3 or 4)", downscale_factor);
+        return -1;
+    }
+    if (downscale_threshold < 0 || min_line_count < 0 || max_number_of_symbols < 0) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Negative decode tuning value");
+        return -1;
+    }
+    options->binarizer = binarizer;
+    options->downscale_threshold = downscale_threshold;
+    options->downscale_factor = downscale_factor;
+    options->is_pure = is_pure;
+    options->min_line_count = min_line_count;
+    options->max_number_of_symbols = max_number_of_symbols;
+    options->return_errors = return_errors;
+    return 0;
+}
+
+// Selects how result text is rendered.
+EXPORT int configure_decode_text_mode(DecodeOptions* options, int text_mode, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    if (text_mode < TEXT_MODE_HRI || text_mode > TEXT_MODE_ESCAPED) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid text mode: %d", text_mode);
+        return -1;
+    }
+    options->text_mode = text_mode;
+    return 0;
+}
+
+// Sets the fallback character set used when a symbol declares no ECI.
+EXPORT int configure_decode_charset(DecodeOptions* options, const char* charset, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    if (!charset || charset[0] == '\0') {
+        options->character_set = 0;
+        return 0;
+    }
+    CharacterSet cs = CharacterSetFromString(charset);
+    if (cs == CharacterSet::Unknown) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Unknown character set: %s", charset);
+        return -1;
+    }
+    options->character_set = static_cast<int>(cs);
+    return 0;
+}
+
+// Selects how EAN-2/EAN-5 add-on symbols are handled.
+EXPORT int configure_decode_ean_add_on(DecodeOptions* options, int ean_add_on, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    if (ean_add_on < EAN_ADD_ON_IGNORE || ean_add_on > EAN_ADD_ON_REQUIRE) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid EAN add-on mode: %d", ean_add_on);
+        return -1;
+    }
+    options->ean_add_on = ean_add_on;
+    return 0;
+}
+
+// Configures the Code 39, ITF and Codabar specific options.
+EXPORT int configure_decode_symbology(DecodeOptions* options, int code39_extended, int code39_check_digit,
+                                      int itf_check_digit, int itf_min_length, int itf_max_length,
+                                      int codabar_start_stop, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    if (itf_min_length < 0 || itf_max_length < 0 || (itf_max_length > 0 && itf_max_length < itf_min_length)) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid ITF length range: %d-%d", itf_min_length, itf_max_length);
+        return -1;
+    }
+    options->code39_extended = code39_extended;
+    options->code39_check_digit = code39_check_digit;
+    options->itf_check_digit = itf_check_digit;
+    options->itf_min_length = itf_min_length;
+    options->itf_max_length = itf_max_length;
+    options->codabar_start_stop = codabar_start_stop;
+    return 0;
+}
+
 // 释放解码选项
 EXPORT void free_options(DecodeOptions* options) {
     delete options;
 }
 
 // 解码单个条码 - 主要实现，直接使用ZXing-cpp
-EXPORT DecodeResult* decode_barcode(const char* image_path, const DecodeOptions* options) {
+EXPORT DecodeResult* decode_barcode(const char* image_path, const DecodeOptions* options, ZXingError* error) {
+    clear_error(error);
     try {
         // 使用stb_image加载图像
         int width, height, channels;
@@ -169,7 +667,7 @@ EXPORT DecodeResult* decode_barcode(const char* image_path, const DecodeOptions*
             stbi_image_free);
         
         if (!buffer) {
-            set_error("Failed to load image: %s (%s)", image_path, stbi_failure_reason());
+            set_error(error, ZXING_ERROR_INVALID_INPUT, "Failed to load image: %s (%s)", image_path, stbi_failure_reason());
             return nullptr;
         }
 
@@ -177,41 +675,36 @@ EXPORT DecodeResult* decode_barcode(const char* image_path, const DecodeOptions*
         auto ImageFormatFromChannels = std::array{ImageFormat::None, ImageFormat::Lum, ImageFormat::LumA, ImageFormat::RGB, ImageFormat::RGBA};
         ImageView image{buffer.get(), width, height, ImageFormatFromChannels.at(channels)};
 
-        // 设置解码选项
-        ReaderOptions hints;
-        hints.setTryHarder(options->try_harder != 0);
-        hints.setTryRotate(options->try_rotate != 0);
-        hints.setTryInvert(options->try_invert != 0);
-        hints.setTryDownscale(options->try_downscale != 0);
-
-        // 解码
-        auto result = ReadBarcode(image, hints);
-        if (!result.isValid()) {
-            set_error("No barcode found");
+        // 解码，返回第一个满足选项要求的条码
+        auto result = read_first_accepted(image, options);
+        if (!accept_barcode(result, options)) {
+            set_decode_failure(result, error);
             return nullptr;
         }
 
-        // 创建结果
-        DecodeResult* decode_result = new DecodeResult();
+        // 创建并填充结果
+        DecodeResult* decode_result = new_decode_result(result, options);
         if (!decode_result) {
-            set_error("Failed to allocate memory for result");
+            set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate memory for result");
             return nullptr;
         }
 
-        // 填充结果
-        decode_result->text = strdup(result.text().c_str());
-        decode_result->format = convert_format(result.format());
-        decode_result->confidence = 1.0f; // 默认置信度为1.0
-
         return decode_result;
     } catch (const std::exception& e) {
-        set_error("Decode error: %s", e.what());
+        set_error(error, ZXING_ERROR_INTERNAL, "Decode error: %s", e.what());
         return nullptr;
     }
 }
 
 // 解码多个条码 - 主要实现，直接使用ZXing-cpp
-EXPORT DecodeResult** decode_barcodes(const char* image_path, const DecodeOptions* options, int* count) {
+EXPORT DecodeResult** decode_barcodes(const char* image_path, const DecodeOptions* options, int* count, ZXingError* error) {
+    clear_error(error);
+    if (!count) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid result count pointer");
+        return nullptr;
+    }
+    *count = 0;
+
     try {
         // 使用stb_image加载图像
         int width, height, channels;
@@ -220,7 +713,7 @@ EXPORT DecodeResult** decode_barcodes(const char* image_path, const DecodeOption
             stbi_image_free);
         
         if (!buffer) {
-            set_error("Failed to load image: %s (%s)", image_path, stbi_failure_reason());
+            set_error(error, ZXING_ERROR_INVALID_INPUT, "Failed to load image: %s (%s)", image_path, stbi_failure_reason());
             return nullptr;
         }
 
@@ -229,47 +722,37 @@ EXPORT DecodeResult** decode_barcodes(const char* image_path, const DecodeOption
         ImageView image{buffer.get(), width, height, ImageFormatFromChannels.at(channels)};
 
         // 设置解码选项
-        ReaderOptions hints;
-        hints.setTryHarder(options->try_harder != 0);
-        hints.setTryRotate(options->try_rotate != 0);
-        hints.setTryInvert(options->try_invert != 0);
-        hints.setTryDownscale(options->try_downscale != 0);
+        ReaderOptions hints = make_reader_options(options);
 
         // 解码
         auto barcodes = ReadBarcodes(image, hints);
+        filter_barcodes(barcodes, options);
         if (barcodes.empty()) {
-            set_error("No barcode found");
+            set_error(error, ZXING_ERROR_NOT_FOUND, "No barcode found");
             return nullptr;
         }
 
-        // 创建结果数组
-        *count = static_cast<int>(barcodes.size());
-        DecodeResult** decode_results = new DecodeResult*[*count];
-        if (!decode_results) {
-            set_error("Failed to allocate memory for results");
-            return nullptr;
-        }
+        // 创建结果数组，*count 仅在全部结果填充成功后写入
+        int n = static_cast<int>(barcodes.size());
+        DecodeResult** decode_results = new DecodeResult*[n];
 
         // 填充结果
-        for (int i = 0; i < *count; i++) {
-            decode_results[i] = new DecodeResult();
+        for (int i = 0; i < n; i++) {
+            decode_results[i] = new_decode_result(barcodes[i], options);
             if (!decode_results[i]) {
-                set_error("Failed to allocate memory for result %d", i);
+                set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate memory for result %d", i);
                 for (int j = 0; j < i; j++) {
                     free_result(decode_results[j]);
                 }
                 delete[] decode_results;
                 return nullptr;
             }
-
-            decode_results[i]->text = strdup(barcodes[i].text().c_str());
-            decode_results[i]->format = convert_format(barcodes[i].format());
-            decode_results[i]->confidence = 1.0f; // 默认置信度为1.0
         }
 
+        *count = n;
         return decode_results;
     } catch (const std::exception& e) {
-        set_error("Decode error: %s", e.what());
+        set_error(error, ZXING_ERROR_INTERNAL, "Decode error: %s", e.what());
         return nullptr;
     }
 }
@@ -286,6 +769,14 @@ EXPORT DecodeResult* decode_result_get(DecodeResult** results, int index) {
 EXPORT void free_result(DecodeResult* result) {
     if (result) {
         free(result->text);
+        free(result->symbology_identifier);
+        free(result->ec_level);
+        free(result->version);
+        free(result->bytes);
+        free(result->bytes_eci);
+        free(result->sequence_id);
+        free(result->error_message);
+        free(result->ean_add_on);
         delete result;
     }
 }
@@ -300,132 +791,244 @@ EXPORT void free_results(DecodeResult** results, int count) {
     }
 }
 
-// 获取错误信息
+// 获取当前线程最近一次错误的错误信息
 EXPORT const char* get_last_error() {
     return last_error;
 }
 
+// 获取当前线程最近一次错误的错误码
+EXPORT int get_last_error_code() {
+    return last_error_code;
+}
+
+// 返回编译该库时的 ZXING_ABI_VERSION
+EXPORT int zxing_abi_version() {
+    return ZXING_ABI_VERSION;
+}
+
 // Decode barcode from raw image file data (PNG/JPEG/BMP etc.)
 // Used by wazero runtime which cannot access filesystem
-EXPORT DecodeResult* decode_barcode_data(const unsigned char* file_data, int file_size, const DecodeOptions* options) {
+EXPORT DecodeResult* decode_barcode_data(const unsigned char* file_data, int file_size, const DecodeOptions* options, ZXingError* error) {
+    clear_error(error);
     if (!file_data || file_size <= 0) {
-        set_error("Invalid image data");
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid image data");
         return nullptr;
     }
 
-    // Load image from memory using stb_image
-    int width, height, channels;
-    unsigned char* img_data = stbi_load_from_memory(file_data, file_size, &width, &height, &channels, 1);
-    if (!img_data) {
-        set_error("Failed to load image from data: %s", stbi_failure_reason());
+    try {
+        // Load image from memory using stb_image; img_data is freed on every path
+        int width, height, channels;
+        std::unique_ptr<stbi_uc, void (*)(void*)> img_data(
+            stbi_load_from_memory(file_data, file_size, &width, &height, &channels, 1),
+            stbi_image_free);
+        if (!img_data) {
+            set_error(error, ZXING_ERROR_INVALID_INPUT, "Failed to load image from data: %s", stbi_failure_reason());
+            return nullptr;
+        }
+
+        // Create ImageView (grayscale) and return the first barcode the options accept
+        ImageView view(img_data.get(), width, height, ImageFormat::Lum);
+        auto barcode = read_first_accepted(view, options);
+        if (!accept_barcode(barcode, options)) {
+            set_decode_failure(barcode, error);
+            return nullptr;
+        }
+
+        DecodeResult* result = new_decode_result(barcode, options);
+        if (!result) {
+            set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate result");
+            return nullptr;
+        }
+
+        return result;
+    } catch (const std::exception& e) {
+        set_error(error, ZXING_ERROR_INTERNAL, "Decode error: %s", e.what());
         return nullptr;
     }
+}
 
-    // Create ImageView (grayscale)
-    ImageView view(img_data, width, height, ImageFormat::Lum);
+// Decodes tightly packed raw pixels without an intermediate encoded image.
+EXPORT DecodeResult* decode_barcode_pixels(const unsigned char* data, int width, int height,
+                                           int channels, const DecodeOptions* options, ZXingError* error) {
+    clear_error(error);
+    if (!data || width <= 0 || height <= 0) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid raw image data or dimensions");
+        return nullptr;
+    }
 
-    // Configure reader options
-    ReaderOptions reader_opts;
-    if (options) {
-        reader_opts.setTryHarder(options->try_harder != 0);
-        reader_opts.setTryRotate(options->try_rotate != 0);
+    ImageFormat image_format = image_format_from_channels(channels);
+    if (image_format == ImageFormat::None) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Unsupported channel count: %d", channels);
+        return nullptr;
     }
 
-    // Decode
-    Barcodes barcodes = ReadBarcodes(view, reader_opts);
+    try {
+        ImageView view(data, width, height, image_format);
+        auto barcode = read_first_accepted(view, options);
+        if (!accept_barcode(barcode, options)) {
+            set_decode_failure(barcode, error);
+            return nullptr;
+        }
 
-    stbi_image_free(img_data);
+        DecodeResult* result = new_decode_result(barcode, options);
+        if (!result) {
+            set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate result text");
+            return nullptr;
+        }
+        return result;
+    } catch (const std::exception& e) {
+        set_error(error, ZXING_ERROR_INTERNAL, "Decode error: %s", e.what());
+        return nullptr;
+    }
+}
 
-    if (barcodes.empty()) {
-        set_error("No barcodes found");
+// Decodes every barcode found in tightly packed raw pixels.
+// The returned array holds *count results and must be released with free_results.
+EXPORT DecodeResult** decode_barcodes_pixels(const unsigned char* data, int width, int height,
+                                             int channels, const DecodeOptions* options, int* count, ZXingError* error) {
+    clear_error(error);
+    if (!count) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid result count pointer");
         return nullptr;
     }
+    *count = 0;
 
-    // Return first result
-    const Barcode& first = barcodes.front();
-    DecodeResult* result = (DecodeResult*)malloc(sizeof(DecodeResult));
-    if (!result) {
-        set_error("Failed to allocate result");
+    if (!data || width <= 0 || height <= 0) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid raw image data or dimensions");
         return nullptr;
     }
 
-    std::string text = first.text();
-    result->text = (char*)malloc(text.size() + 1);
-    if (result->text) {
-        strcpy(result->text, text.c_str());
+    ImageFormat image_format = image_format_from_channels(channels);
+    if (image_format == ImageFormat::None) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Unsupported channel count: %d", channels);
+        return nullptr;
     }
-    result->format = convert_format(first.format());
-    result->confidence = 1.0f;
 
-    return result;
+    try {
+        ImageView view(data, width, height, image_format);
+        auto barcodes = ReadBarcodes(view, make_reader_options(options));
+        filter_barcodes(barcodes, options);
+        if (barcodes.empty()) {
+            set_error(error, ZXING_ERROR_NOT_FOUND, "No barcode found");
+            return nullptr;
+        }
+
+        int n = static_cast<int>(barcodes.size());
+        DecodeResult** results = new DecodeResult*[n];
+        for (int i = 0; i < n; i++) {
+            results[i] = new_decode_result(barcodes[i], options);
+            if (!results[i]) {
+                set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate result %d", i);
+                free_results(results, i);
+                return nullptr;
+            }
+        }
+
+        *count = n;
+        return results;
+    } catch (const std::exception& e) {
+        set_error(error, ZXING_ERROR_INTERNAL, "Decode error: %s", e.what());
+        return nullptr;
+    }
 }
 
-// Decodes tightly packed raw pixels without an intermediate encoded image.
-EXPORT DecodeResult* decode_barcode_pixels(const unsigned char* data, int width, int height,
-                                           int channels, const DecodeOptions* options) {
-    if (!data || width <= 0 || height <= 0) {
-        set_error("Invalid raw image data or dimensions");
+// 创建默认编码选项
+EXPORT EncodeOptions* create_encode_options() {
+    EncodeOptions* options = new EncodeOptions();
+    options->format = FORMAT_QR_CODE;
+    options->ec_level[0] = '\0';
+    return options;
+}
+
+// 设置编码选项
+EXPORT int configure_encode_options(EncodeOptions* options, int format, const char* ec_level, ZXingError* error) {
+    clear_error(error);
+    if (!options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid options");
+        return -1;
+    }
+    options->format = format;
+    options->ec_level[0] = '\0';
+    if (ec_level) {
+        if (strlen(ec_level) >= sizeof(options->ec_level)) {
+            set_error(error, ZXING_ERROR_INVALID_INPUT, "Error correction level too long: %s", ec_level);
+            return -1;
+        }
+        strcpy(options->ec_level, ec_level);
+    }
+    return 0;
+}
+
+// 释放编码选项
+EXPORT void free_encode_options(EncodeOptions* options) {
+    delete options;
+}
+
+// 将文本编码为条码模块矩阵。缩放、静区等渲染工作由调用方完成，
+// 这样 CGO 和 WASM 后端可以共用同一套渲染逻辑。
+EXPORT EncodeResult* encode_barcode(const char* text, int text_length, const EncodeOptions* options, ZXingError* error) {
+    clear_error(error);
+    if (!text || text_length < 0 || !options) {
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Invalid parameters");
         return nullptr;
     }
 
-    auto formats = std::array{ImageFormat::None, ImageFormat::Lum, ImageFormat::LumA,
-                              ImageFormat::RGB, ImageFormat::RGBA};
-    if (channels < 1 || channels >= static_cast<int>(formats.size())) {
-        set_error("Unsupported channel count: %d", channels);
+    ZXing::BarcodeFormat format = to_zxing_format(options->format);
+    if (format == ZXing::BarcodeFormat::None) {
+        set_error(error, ZXING_ERROR_UNSUPPORTED, "Unsupported encode format: %d", options->format);
         return nullptr;
     }
 
     try {
-        ImageView view(data, width, height, formats.at(channels));
-        ReaderOptions reader_opts;
-        if (options) {
-            reader_opts.setTryHarder(options->try_harder != 0);
-            reader_opts.setTryRotate(options->try_rotate != 0);
-            reader_opts.setTryInvert(options->try_invert != 0);
-            reader_opts.setTryDownscale(options->try_downscale != 0);
-
-            std::vector<ZXing::BarcodeFormat> selected_formats;
-            if (options->formats != FORMAT_ALL && options->formats != FORMAT_NONE) {
-                if (options->formats & FORMAT_QR_CODE) selected_formats.push_back(ZXing::BarcodeFormat::QRCode);
-                if (options->formats & FORMAT_AZTEC) selected_formats.push_back(ZXing::BarcodeFormat::Aztec);
-                if (options->formats & FORMAT_CODABAR) selected_formats.push_back(ZXing::BarcodeFormat::Codabar);
-                if (options->formats & FORMAT_CODE_39) selected_formats.push_back(ZXing::BarcodeFormat::Code39);
-                if (options->formats & FORMAT_CODE_93) selected_formats.push_back(ZXing::BarcodeFormat::Code93);
-                if (options->formats & FORMAT_CODE_128) selected_formats.push_back(ZXing::BarcodeFormat::Code128);
-                if (options->formats & FORMAT_DATA_MATRIX) selected_formats.push_back(ZXing::BarcodeFormat::DataMatrix);
-                if (options->formats & FORMAT_EAN_8) selected_formats.push_back(ZXing::BarcodeFormat::EAN8);
-                if (options->formats & FORMAT_EAN_13) selected_formats.push_back(ZXing::BarcodeFormat::EAN13);
-                if (options->formats & FORMAT_ITF) selected_formats.push_back(ZXing::BarcodeFormat::ITF);
-                if (options->formats & FORMAT_MAXICODE) selected_formats.push_back(ZXing::BarcodeFormat::MaxiCode);
-                if (options->formats & FORMAT_PDF_417) selected_formats.push_back(ZXing::BarcodeFormat::PDF417);
-                if (options->formats & FORMAT_UPC_A) selected_formats.push_back(ZXing::BarcodeFormat::UPCA);
-                if (options->formats & FORMAT_UPC_E) selected_formats.push_back(ZXing::BarcodeFormat::UPCE);
-                reader_opts.setFormats(ZXing::BarcodeFormats(std::move(selected_formats)));
-            }
+        std::string creator_opts;
+        if (options->ec_level[0] != '\0') {
+            creator_opts = std::string("ecLevel=") + options->ec_level;
         }
+        auto barcode = CreateBarcodeFromText(std::string_view(text, text_length),
+                                             CreatorOptions(format, creator_opts));
 
-        auto barcode = ReadBarcode(view, reader_opts);
-        if (!barcode.isValid()) {
-            set_error("No barcode found");
+        // 每个模块渲染为一个像素，不加静区和人类可读文本
+        auto image = WriteBarcodeToImage(barcode, WriterOptions().scale(1).addQuietZones(false).addHRT(false));
+        int width = image.width();
+        int height = image.height();
+        if (width <= 0 || height <= 0) {
+            set_error(error, ZXING_ERROR_INTERNAL, "Encoder returned an empty symbol");
             return nullptr;
         }
 
-        auto* result = new DecodeResult();
-        result->text = strdup(barcode.text().c_str());
-        if (!result->text) {
+        auto* result = new EncodeResult();
+        result->modules = static_cast<unsigned char*>(malloc(static_cast<size_t>(width) * height));
+        if (!result->modules) {
             delete result;
-            set_error("Failed to allocate result text");
+            set_error(error, ZXING_ERROR_INTERNAL, "Failed to allocate symbol of %dx%d modules", width, height);
             return nullptr;
         }
-        result->format = convert_format(barcode.format());
-        result->confidence = 1.0f;
+        result->width = width;
+        result->height = height;
+        for (int y = 0; y < height; y++) {
+            for (int x = 0; x < width; x++) {
+                result->modules[y * width + x] = *image.data(x, y) < 128 ? 1 : 0;
+            }
+        }
         return result;
+    } catch (const std::invalid_argument& e) {
+        // 写入器以 invalid_argument 报告内容不符合格式要求（如 EAN-13 含非数字字符）
+        set_error(error, ZXING_ERROR_INVALID_INPUT, "Encode error: %s", e.what());
+        return nullptr;
     } catch (const std::exception& e) {
-        set_error("Decode error: %s", e.what());
+        set_error(error, ZXING_ERROR_INTERNAL, "Encode error: %s", e.what());
         return nullptr;
     }
 }
 
+// 释放编码结果
+EXPORT void free_encode_result(EncodeResult* result) {
+    if (result) {
+        free(result->modules);
+        delete result;
+    }
+}
+
 // Memory allocation wrappers for WASM export.
 // In Emscripten standalone mode, malloc/free may not be directly exportable,
 // so we provide thin wrappers that can be reliably exported.
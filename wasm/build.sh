@@ -1,7 +1,7 @@
 #!/bin/bash
 
 # ZXing WASM 构建脚本
-# 使用 Emscripten 编译简化版 zxing 为 WebAssembly
+# 使用 Emscripten 编译 js/wasm 后端的胶水代码 wrapper.cpp 为 WebAssembly
 
 set -e
 
@@ -23,21 +23,43 @@ fi
 BUILD_DIR="build"
 mkdir -p $BUILD_DIR
 
+# 检出与预编译库相同版本的 zxing-cpp
+ROOT_DIR="$(cd "$(dirname "$0")/.." && pwd)"
+ZXING_CPP_VERSION="v3.0.2"
+git -C "$ROOT_DIR" submodule update --init zxing-cpp
+git -C "$ROOT_DIR/zxing-cpp" checkout --quiet "$ZXING_CPP_VERSION"
+
+echo "编译 zxing-cpp 静态库..."
+
+emcmake cmake -S "$ROOT_DIR/zxing-cpp" -B $BUILD_DIR/zxing-cpp \
+    -DCMAKE_BUILD_TYPE=Release \
+    -DBUILD_SHARED_LIBS=OFF \
+    -DZXING_READERS=ON \
+    -DZXING_WRITERS=ON \
+    -DZXING_EXAMPLES=OFF \
+    -DZXING_BLACKBOX_TESTS=OFF \
+    -DZXING_UNIT_TESTS=OFF \
+    -DZXING_PYTHON_MODULE=OFF \
+    -DZXING_C_API=OFF
+emmake make -C $BUILD_DIR/zxing-cpp -j"$(nproc 2>/dev/null || echo 4)"
+
 echo "编译 C++ 源码为 WASM..."
 
-# 编译简化版 zxing 为 WASM
+# 编译 wrapper.cpp，导出 decode_image_data、decode_multiple_barcodes 和 encode_text_to_qr
 emcc \
     -O3 \
     -s WASM=1 \
-    -s EXPORTED_RUNTIME_METHODS='["ccall", "cwrap", "getValue", "setValue"]' \
-    -s EXPORTED_FUNCTIONS='["_decode_image_data", "_encode_text_to_qr", "_free_decode_result", "_free_encode_result", "_malloc", "_free"]' \
     -s ALLOW_MEMORY_GROWTH=1 \
     -s MODULARIZE=1 \
     -s EXPORT_NAME='ZXingWASM' \
     -s ENVIRONMENT='web,worker' \
     --bind \
-    -std=c++17 \
-    zxing_simple.cpp \
+    -std=c++20 \
+    -I"$ROOT_DIR/include" \
+    -I"$ROOT_DIR/zxing-cpp/core/src" \
+    -I$BUILD_DIR/zxing-cpp/core \
+    wrapper.cpp \
+    $BUILD_DIR/zxing-cpp/core/libZXing.a \
     -o $BUILD_DIR/zxing.js
 
 if [ $? -eq 0 ]; then
@@ -1,110 +1,200 @@
 // ZXing WASM 包装器
-// 提供简化的 C 接口用于 WASM 导出
+// 通过 embind 为浏览器中的 js/wasm 后端（pkg/wasm/runtime_js.go）导出解码和编码函数。
+// 结果以普通 JS 对象返回，Go 端用 JSON.stringify 读取，字段名与 wasm.DecodeResult 的 json 标签一致。
 
+#include "zxing.h"
+#include "ReadBarcode.h"
+#include "WriteBarcode.h"
+#include "GTIN.h"
 #include <string>
 #include <vector>
-#include <memory>
+#include <exception>
+#include <stdexcept>
 #include <emscripten/bind.h>
+#include <emscripten/val.h>
 
-// 简化的结果结构
-struct DecodeResult {
-    bool success;
-    std::string text;
-    std::string format;
-    int error_code;
-    std::string error_message;
-};
-
-struct EncodeResult {
-    bool success;
-    int width;
-    int height;
-    std::vector<uint8_t> data;
-    int error_code;
-    std::string error_message;
-};
-
-// 解码图像数据
-DecodeResult decode_image_data(const std::vector<uint8_t>& image_data, 
-                              int width, int height, int channels) {
-    DecodeResult result;
-    result.success = false;
-    result.error_code = 0;
-    
+using namespace ZXing;
+using emscripten::val;
+
+// 按通道数返回图像格式，不支持的通道数返回 ImageFormat::None
+static ImageFormat image_format_from_channels(int channels) {
+    switch (channels) {
+        case 1: return ImageFormat::Lum;
+        case 2: return ImageFormat::LumA;
+        case 3: return ImageFormat::RGB;
+        case 4: return ImageFormat::RGBA;
+        default: return ImageFormat::None;
+    }
+}
+
+// 按 ZXingErrorCode 返回解码错误码
+static int error_code(const Error& error) {
+    switch (error.type()) {
+        case Error::Checksum: return ZXING_ERROR_CHECKSUM;
+        case Error::Format: return ZXING_ERROR_FORMAT;
+        case Error::Unsupported: return ZXING_ERROR_UNSUPPORTED;
+        default: return ZXING_ERROR_NONE;
+    }
+}
+
+// 创建失败结果
+static val failure(int code, const std::string& message) {
+    val result = val::object();
+    result.set("success", false);
+    result.set("error_code", code);
+    result.set("error_message", message);
+    return result;
+}
+
+// 将字节数组转换为 JS 数组
+static val byte_array(const ByteArray& bytes) {
+    val array = val::array();
+    for (size_t i = 0; i < bytes.size(); i++) {
+        array.call<void>("push", static_cast<int>(bytes[i]));
+    }
+    return array;
+}
+
+// 将条码转换为 JS 结果对象，角点顺序为左上、右上、右下、左下
+static val barcode_result(const Barcode& barcode) {
+    val result = val::object();
+    result.set("success", true);
+    result.set("text", barcode.text());
+    result.set("format", ToString(barcode.format()));
+    result.set("error_code", error_code(barcode.error()));
+    result.set("error_message", barcode.error().msg());
+
+    const auto& position = barcode.position();
+    val points = val::array();
+    for (const auto& p : {position.topLeft(), position.topRight(), position.bottomRight(), position.bottomLeft()}) {
+        val point = val::object();
+        point.set("X", p.x);
+        point.set("Y", p.y);
+        points.call<void>("push", point);
+    }
+    result.set("points", points);
+
+    result.set("symbology_identifier", barcode.symbologyIdentifier());
+    result.set("ec_level", barcode.ecLevel());
+    result.set("version", barcode.version());
+    result.set("orientation", barcode.orientation());
+    result.set("is_mirrored", barcode.isMirrored());
+    result.set("is_inverted", barcode.isInverted());
+    result.set("line_count", barcode.lineCount());
+    result.set("content_type", static_cast<int>(barcode.contentType()));
+    result.set("has_eci", barcode.hasECI());
+    result.set("bytes", byte_array(barcode.bytes()));
+    result.set("bytes_eci", byte_array(barcode.bytesECI()));
+    result.set("sequence_size", barcode.sequenceSize());
+    result.set("sequence_index", barcode.sequenceIndex());
+    result.set("sequence_id", barcode.sequenceId());
+    result.set("add_on", GTIN::EanAddOn(barcode));
+    return result;
+}
+
+// 读取图像中的条码，max_symbols 为 0 表示不限数量。
+// 图像数据无效时返回 false 并写入 error
+static bool read_barcodes(const val& image_data, int width, int height, int channels, int max_symbols,
+                          Barcodes& barcodes, val& error) {
+    ImageFormat format = image_format_from_channels(channels);
+    if (format == ImageFormat::None) {
+        error = failure(ZXING_ERROR_INVALID_INPUT, "Unsupported channel count: " + std::to_string(channels));
+        return false;
+    }
+    std::vector<uint8_t> data = emscripten::convertJSArrayToNumberVector<uint8_t>(image_data);
+    if (width <= 0 || height <= 0 || data.size() < static_cast<size_t>(width) * height * channels) {
+        error = failure(ZXING_ERROR_INVALID_INPUT, "Invalid raw image data or dimensions");
+        return false;
+    }
+
+    ReaderOptions options;
+    options.setTryHarder(true).setTryRotate(true).setTryDownscale(true).setMaxNumberOfSymbols(max_symbols);
+    barcodes = ReadBarcodes(ImageView(data.data(), width, height, format), options);
+    return true;
+}
+
+// 解码图像数据中的第一个条码
+val decode_image_data(const val& image_data, int width, int height, int channels) {
     try {
-        // TODO: 集成实际的 zxing 解码逻辑
-        // 这里需要根据实际的 zxing 库接口进行实现
-        
-        // 示例实现
-        if (image_data.empty()) {
-            result.error_code = 1;
-            result.error_message = "Empty image data";
-            return result;
+        Barcodes barcodes;
+        val error;
+        if (!read_barcodes(image_data, width, height, channels, 1, barcodes, error)) {
+            return error;
         }
-        
-        // 实际解码逻辑将在这里实现
-        result.success = true;
-        result.text = "Sample decoded text";
-        result.format = "QR_CODE";
-        
+        for (const auto& barcode : barcodes) {
+            if (barcode.isValid()) {
+                return barcode_result(barcode);
+            }
+        }
+        return failure(ZXING_ERROR_NOT_FOUND, "No barcode found");
     } catch (const std::exception& e) {
-        result.error_code = 2;
-        result.error_message = e.what();
+        return failure(ZXING_ERROR_INTERNAL, std::string("Decode error: ") + e.what());
     }
-    
-    return result;
 }
 
-// 编码文本为二维码
-EncodeResult encode_text_to_qr(const std::string& text, int width, int height) {
-    EncodeResult result;
-    result.success = false;
-    result.error_code = 0;
-    result.width = width;
-    result.height = height;
-    
+// 解码图像数据中的所有条码。返回结果数组，失败时返回只含一个失败结果的数组
+val decode_multiple_barcodes(const val& image_data, int width, int height, int channels) {
+    val results = val::array();
     try {
-        // TODO: 集成实际的 zxing 编码逻辑
-        
-        if (text.empty()) {
-            result.error_code = 1;
-            result.error_message = "Empty text";
-            return result;
+        Barcodes barcodes;
+        val error;
+        if (!read_barcodes(image_data, width, height, channels, 0, barcodes, error)) {
+            results.call<void>("push", error);
+            return results;
+        }
+        for (const auto& barcode : barcodes) {
+            if (barcode.isValid()) {
+                results.call<void>("push", barcode_result(barcode));
+            }
         }
-        
-        // 创建示例数据
-        result.data.resize(width * height);
-        // 实际编码逻辑将在这里实现
-        
-        result.success = true;
-        
     } catch (const std::exception& e) {
-        result.error_code = 2;
-        result.error_message = e.what();
+        results.call<void>("push", failure(ZXING_ERROR_INTERNAL, std::string("Decode error: ") + e.what()));
+    }
+    return results;
+}
+
+// 编码文本为二维码，输出 width x height 的灰度像素（0 为黑，255 为白），含默认静区
+val encode_text_to_qr(const std::string& text, int width, int height) {
+    if (text.empty()) {
+        return failure(ZXING_ERROR_INVALID_INPUT, "Empty text");
+    }
+    if (width <= 0 || height <= 0) {
+        return failure(ZXING_ERROR_INVALID_INPUT, "Invalid dimensions");
+    }
+
+    try {
+        auto barcode = CreateBarcodeFromText(text, CreatorOptions(ZXing::BarcodeFormat::QRCode));
+        auto symbol = WriteBarcodeToImage(barcode, WriterOptions().scale(1).addQuietZones(true).addHRT(false));
+        if (symbol.width() <= 0 || symbol.height() <= 0) {
+            return failure(ZXING_ERROR_INTERNAL, "Encoder returned an empty symbol");
+        }
+
+        // 最近邻缩放到请求尺寸
+        val data = val::array();
+        for (int y = 0; y < height; y++) {
+            for (int x = 0; x < width; x++) {
+                data.call<void>("push", static_cast<int>(*symbol.data(x * symbol.width() / width, y * symbol.height() / height)));
+            }
+        }
+
+        val result = val::object();
+        result.set("success", true);
+        result.set("width", width);
+        result.set("height", height);
+        result.set("data", data);
+        result.set("error_code", ZXING_ERROR_NONE);
+        result.set("error_message", std::string());
+        return result;
+    } catch (const std::invalid_argument& e) {
+        return failure(ZXING_ERROR_INVALID_INPUT, std::string("Encode error: ") + e.what());
+    } catch (const std::exception& e) {
+        return failure(ZXING_ERROR_INTERNAL, std::string("Encode error: ") + e.what());
     }
-    
-    return result;
 }
 
 // Emscripten 绑定
 EMSCRIPTEN_BINDINGS(zxing_module) {
-    emscripten::value_object<DecodeResult>("DecodeResult")
-        .field("success", &DecodeResult::success)
-        .field("text", &DecodeResult::text)
-        .field("format", &DecodeResult::format)
-        .field("error_code", &DecodeResult::error_code)
-        .field("error_message", &DecodeResult::error_message);
-    
-    emscripten::value_object<EncodeResult>("EncodeResult")
-        .field("success", &EncodeResult::success)
-        .field("width", &EncodeResult::width)
-        .field("height", &EncodeResult::height)
-        .field("data", &EncodeResult::data)
-        .field("error_code", &EncodeResult::error_code)
-        .field("error_message", &EncodeResult::error_message);
-    
     emscripten::function("decode_image_data", &decode_image_data);
+    emscripten::function("decode_multiple_barcodes", &decode_multiple_barcodes);
     emscripten::function("encode_text_to_qr", &encode_text_to_qr);
-    
-    emscripten::register_vector<uint8_t>("VectorUint8");
-}
\ No newline at end of file
+}